@@ -0,0 +1,312 @@
+// analysis.go проверяет операции всех файлов запросов вместе до начала изменений
+//   - Одинаковые строки (в одном или разных файлах) выполняются один раз
+//   - Повторное добавление или удаление пользователя в разных строках выводится как предупреждение
+//   - Логины сравниваются после нормализации: префикс стратегии, домен, регистр, псевдонимы
+//   - Добавление и удаление одного пользователя в одной роли считается конфликтом:
+//     без правила приоритета в config.yaml обработка не начинается
+//   - Удаление или переименование роли, в которую другие строки добавляют пользователей, тоже конфликт.
+//     Правило приоритета его не разрешает: итог зависел бы от порядка файлов, обработка не начинается
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Правила приоритета при конфликте добавления и удаления пользователя
+const (
+	precedenceAdd    = "add"    // пользователь остаётся в роли: удаление пропускается
+	precedenceRemove = "remove" // пользователь удаляется из роли: добавление пропускается
+)
+
+// ConflictsConfig содержит настройки разрешения конфликтов между строками запросов
+type ConflictsConfig struct {
+	Precedence string `yaml:"precedence"` // Правило приоритета: add, remove
+}
+
+// isPrecedence проверяет название правила приоритета
+func isPrecedence(precedence string) bool {
+	return precedence == precedenceAdd || precedence == precedenceRemove
+}
+
+// operationRef указывает на операцию в списке прочитанных файлов
+type operationRef struct {
+	batch int
+	index int
+}
+
+// membershipKey определяет пользователя в роли: инстанс, окружение, клиент, роль и логин
+type membershipKey struct {
+	role  string
+	login string
+}
+
+// membershipChange содержит изменение членства пользователя в роли, которое выполнит операция
+type membershipChange struct {
+	ref   operationRef
+	add   bool
+	login string // Логин в том виде, как он указан в строке
+}
+
+// analyzeBatches объединяет операции всех файлов, убирает повторяющиеся строки и проверяет конфликты.
+// Возвращает ошибку, если есть конфликты, а правило приоритета не задано
+func analyzeBatches(batches []requestBatch) ([]requestBatch, error) {
+	batches = dropDuplicateRows(batches)
+
+	changes := collectMembershipChanges(batches)
+	roleChanges := collectRoleChanges(batches)
+	keys := make([]membershipKey, 0, len(changes))
+	for key := range changes {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b membershipKey) int {
+		return strings.Compare(a.role+"|"+a.login, b.role+"|"+b.login)
+	})
+
+	var conflicts []string
+	dropped := make(map[operationRef][]string)
+	precedence := config.Conflicts.Precedence
+	roleAdds := make(map[string][]membershipChange)
+	for _, key := range keys {
+		list := changes[key]
+		var adds, removes []membershipChange
+		for _, change := range list {
+			if change.add {
+				adds = append(adds, change)
+			} else {
+				removes = append(removes, change)
+			}
+		}
+
+		roleAdds[key.role] = append(roleAdds[key.role], adds...)
+		if len(adds) > 1 {
+			logWarn("Роль %s: пользователь %s добавляется несколько раз: %s", key.role, key.login, describeChanges(batches, adds))
+		}
+		if len(removes) > 1 {
+			logWarn("Роль %s: пользователь %s удаляется несколько раз: %s", key.role, key.login, describeChanges(batches, removes))
+		}
+		if len(adds) == 0 || len(removes) == 0 {
+			continue
+		}
+
+		conflict := fmt.Sprintf("роль %s: пользователь %s добавляется (%s) и удаляется (%s)", key.role, key.login,
+			describeChanges(batches, adds), describeChanges(batches, removes))
+		conflicts = append(conflicts, conflict)
+
+		switch precedence {
+		case precedenceAdd:
+			for _, change := range removes {
+				dropped[change.ref] = append(dropped[change.ref], change.login)
+			}
+		case precedenceRemove:
+			for _, change := range adds {
+				dropped[change.ref] = append(dropped[change.ref], change.login)
+			}
+		}
+	}
+
+	var roleConflicts []string
+	roles := make([]string, 0, len(roleChanges))
+	for role := range roleChanges {
+		roles = append(roles, role)
+	}
+	slices.Sort(roles)
+	for _, role := range roles {
+		adds := uniqueOperations(roleAdds[role])
+		if len(adds) == 0 {
+			continue
+		}
+		roleConflicts = append(roleConflicts, fmt.Sprintf("роль %s удаляется или переименовывается (%s), а в неё добавляются пользователи (%s)",
+			role, describeChanges(batches, roleChanges[role]), describeChanges(batches, adds)))
+	}
+
+	if len(roleConflicts) > 0 {
+		for _, conflict := range roleConflicts {
+			logError("Конфликт: %s", conflict)
+		}
+		return nil, fmt.Errorf("найдено конфликтов с удалением или переименованием роли: %d. Правило приоритета их не разрешает, "+
+			"исправьте файлы запросов", len(roleConflicts))
+	}
+	if len(conflicts) == 0 {
+		return batches, nil
+	}
+	if precedence == "" {
+		for _, conflict := range conflicts {
+			logError("Конфликт: %s", conflict)
+		}
+		return nil, fmt.Errorf("найдено конфликтов: %d. Исправьте файлы запросов или задайте conflicts.precedence "+
+			"в config.yaml (%s, %s)", len(conflicts), precedenceAdd, precedenceRemove)
+	}
+
+	for _, conflict := range conflicts {
+		logWarn("Конфликт: %s. Применяется правило %s", conflict, precedence)
+	}
+	return dropConflictingLogins(batches, dropped), nil
+}
+
+// collectMembershipChanges собирает изменения членства по пользователю в роли.
+// Копирование и перенос "всех участников" не раскрываются: их состав известен только при выполнении
+func collectMembershipChanges(batches []requestBatch) map[membershipKey][]membershipChange {
+	changes := make(map[membershipKey][]membershipChange)
+	add := func(ref operationRef, operation *Operation, role string, isAdd bool) {
+		for _, login := range operation.ldaps {
+			if login == allMembersMarker {
+				continue
+			}
+			key := membershipKey{role: roleKey(operation, role), login: operation.loginKey(login)}
+			changes[key] = append(changes[key], membershipChange{ref: ref, add: isAdd, login: login})
+		}
+	}
+
+	for b, batch := range batches {
+		for i := range batch.operations {
+			operation := &batch.operations[i]
+			ref := operationRef{batch: b, index: i}
+			switch operation.action {
+			case actionCreate, actionAssociate:
+				add(ref, operation, operation.roleName, true)
+			case actionRemove:
+				add(ref, operation, operation.roleName, false)
+			case actionCopyMembers:
+				add(ref, operation, operation.targetRoleName, true)
+			case actionMoveMembers:
+				add(ref, operation, operation.targetRoleName, true)
+				add(ref, operation, operation.roleName, false)
+			}
+		}
+	}
+	return changes
+}
+
+// collectRoleChanges собирает строки, которые удаляют или переименовывают роль, по ключу роли
+func collectRoleChanges(batches []requestBatch) map[string][]membershipChange {
+	changes := make(map[string][]membershipChange)
+	for b, batch := range batches {
+		for i := range batch.operations {
+			operation := &batch.operations[i]
+			if operation.action != actionDelete && operation.action != actionRename {
+				continue
+			}
+			role := roleKey(operation, operation.roleName)
+			changes[role] = append(changes[role], membershipChange{ref: operationRef{batch: b, index: i}})
+		}
+	}
+	return changes
+}
+
+// uniqueOperations оставляет по одному изменению на строку, сохраняя порядок
+func uniqueOperations(changes []membershipChange) []membershipChange {
+	seen := make(map[operationRef]bool)
+	var unique []membershipChange
+	for _, change := range changes {
+		if !seen[change.ref] {
+			seen[change.ref] = true
+			unique = append(unique, change)
+		}
+	}
+	slices.SortFunc(unique, func(a, b membershipChange) int {
+		if a.ref.batch != b.ref.batch {
+			return a.ref.batch - b.ref.batch
+		}
+		return a.ref.index - b.ref.index
+	})
+	return unique
+}
+
+// dropDuplicateRows убирает строки, полностью повторяющие более раннюю строку этого или другого файла
+func dropDuplicateRows(batches []requestBatch) []requestBatch {
+	seen := make(map[string]*Operation)
+	for b := range batches {
+		operations := batches[b].operations[:0:0]
+		for i := range batches[b].operations {
+			operation := &batches[b].operations[i]
+			key := rowKey(operation)
+			if first, ok := seen[key]; ok {
+				logWarn("%s повторяет %s и будет пропущена", operationLocation(operation), operationLocation(first))
+				continue
+			}
+			seen[key] = operation
+			operations = append(operations, *operation)
+		}
+		batches[b].operations = operations
+	}
+	return batches
+}
+
+// dropConflictingLogins убирает логины, проигравшие по правилу приоритета. Операция, у которой
+// не осталось логинов, пропускается: для копирования и переноса пустой список означал бы всех участников
+func dropConflictingLogins(batches []requestBatch, dropped map[operationRef][]string) []requestBatch {
+	if len(dropped) == 0 {
+		return batches
+	}
+
+	for b := range batches {
+		operations := batches[b].operations[:0:0]
+		for i, operation := range batches[b].operations {
+			logins := dropped[operationRef{batch: b, index: i}]
+			if len(logins) == 0 {
+				operations = append(operations, operation)
+				continue
+			}
+
+			operation.ldaps = slices.DeleteFunc(slices.Clone(operation.ldaps), func(login string) bool {
+				return slices.Contains(logins, login)
+			})
+			logWarn("%s: по правилу приоритета пропущены пользователи: %s",
+				operationLocation(&operation), strings.Join(logins, ", "))
+			if len(operation.ldaps) == 0 {
+				logWarn("%s: не осталось пользователей, строка пропущена", operationLocation(&operation))
+				continue
+			}
+			operations = append(operations, operation)
+		}
+		batches[b].operations = operations
+	}
+	return batches
+}
+
+// roleKey возвращает ключ роли: инстанс, окружение, клиент и имя роли
+func roleKey(operation *Operation, role string) string {
+	return strings.Join([]string{operation.instance, operation.environment, operation.ClientIdName, role}, "/")
+}
+
+// rowKey возвращает ключ строки для поиска повторов: порядок логинов и опций не важен
+func rowKey(operation *Operation) string {
+	logins := make([]string, 0, len(operation.ldaps))
+	for _, login := range operation.ldaps {
+		logins = append(logins, operation.loginKey(login))
+	}
+	slices.Sort(logins)
+	options := slices.Clone(operation.options)
+	slices.Sort(options)
+
+	return strings.Join([]string{operation.instance, operation.environment, operation.action, operation.ClientIdName,
+		operation.roleName, operation.targetRoleName, strings.Join(logins, ","), strings.Join(options, ",")}, "|")
+}
+
+// loginKey возвращает логин для сравнения строк: без префикса стратегии, нормализованный так же,
+// как перед поиском в Keycloak. Для поиска не по логину к значению добавляется стратегия
+func (o *Operation) loginKey(ldap string) string {
+	strategy, value, _ := o.identifierStrategy(ldap)
+	key := canonicalIdentifier(strategy, value).value
+	if strategy != identifierUsername {
+		key = strategy + ":" + key
+	}
+	return key
+}
+
+// operationLocation возвращает файл и строку операции для сообщений
+func operationLocation(operation *Operation) string {
+	return operation.sourceFile + ", строка " + strconv.Itoa(operation.rowNum)
+}
+
+// describeChanges перечисляет строки, в которых выполняются изменения
+func describeChanges(batches []requestBatch, changes []membershipChange) string {
+	locations := make([]string, 0, len(changes))
+	for _, change := range changes {
+		locations = append(locations, operationLocation(&batches[change.ref.batch].operations[change.ref.index]))
+	}
+	return strings.Join(locations, "; ")
+}
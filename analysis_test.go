@@ -0,0 +1,109 @@
+package main
+
+import (
+	"io"
+	"log"
+	"strings"
+	"testing"
+)
+
+// discardLog направляет лог в никуда на время теста: анализ пишет предупреждения через logger
+func discardLog(t *testing.T) {
+	saved := logger
+	logger = log.New(io.Discard, "", 0)
+	t.Cleanup(func() { logger = saved })
+}
+
+// testOperation создаёт операцию строки файла запросов без обращения к Keycloak
+func testOperation(rowNum int, action, role string, logins ...string) Operation {
+	return Operation{
+		instance:     "Employee",
+		environment:  "Dev",
+		ClientIdName: "crm",
+		action:       action,
+		roleName:     role,
+		ldaps:        logins,
+		sourceFile:   "request.xlsx",
+		rowNum:       rowNum,
+	}
+}
+
+func TestLoginKey(t *testing.T) {
+	saved := config.Normalize
+	defer func() { config.Normalize = saved }()
+	config.Normalize = NormalizeConfig{Domains: []string{"corp.ru"}}
+
+	operation := testOperation(2, actionAssociate, "viewer")
+	tests := map[string]string{
+		"ivanov":                   "ivanov",
+		"IVANOV.":                  "ivanov",
+		`CORP\ivanov`:              "ivanov",
+		"ivanov@corp.ru":           "ivanov",
+		"username:ivanov":          "ivanov",
+		"email:Ivanov@Corp.ru":     "email:ivanov@corp.ru",
+		"ivаnov":                   "ivanov", // кириллическая "а"
+		"username: IVANOV@corp.ru": "ivanov",
+	}
+	for login, want := range tests {
+		if got := operation.loginKey(login); got != want {
+			t.Errorf("loginKey(%q) = %q, ожидалось %q", login, got, want)
+		}
+	}
+}
+
+func TestAnalyzeBatchesNormalizesLogins(t *testing.T) {
+	discardLog(t)
+	saved := config.Conflicts
+	defer func() { config.Conflicts = saved }()
+
+	batches := func() []requestBatch {
+		return []requestBatch{{file: "request.xlsx", operations: []Operation{
+			testOperation(2, actionAssociate, "viewer", "ivanov"),
+			testOperation(3, actionRemove, "viewer", `CORP\IVANOV`, "petrov"),
+		}}}
+	}
+
+	config.Conflicts = ConflictsConfig{}
+	if _, err := analyzeBatches(batches()); err == nil {
+		t.Fatal("конфликт логинов в разном написании не найден")
+	}
+
+	config.Conflicts = ConflictsConfig{Precedence: precedenceAdd}
+	result, err := analyzeBatches(batches())
+	if err != nil {
+		t.Fatalf("analyzeBatches: %v", err)
+	}
+	removal := result[0].operations[1]
+	if strings.Join(removal.ldaps, ",") != "petrov" {
+		t.Errorf("после правила add у удаления остались логины %v, ожидался только petrov", removal.ldaps)
+	}
+}
+
+func TestAnalyzeBatchesRoleChanges(t *testing.T) {
+	discardLog(t)
+	saved := config.Conflicts
+	defer func() { config.Conflicts = saved }()
+	config.Conflicts = ConflictsConfig{}
+
+	for _, precedence := range []string{"", precedenceAdd, precedenceRemove} {
+		config.Conflicts = ConflictsConfig{Precedence: precedence}
+		for _, action := range []string{actionDelete, actionRename} {
+			batches := []requestBatch{{file: "request.xlsx", operations: []Operation{
+				testOperation(2, actionAssociate, "viewer", "ivanov"),
+				testOperation(3, action, "viewer"),
+			}}}
+			if _, err := analyzeBatches(batches); err == nil {
+				t.Errorf("%s роли, в которую добавляются пользователи, не считается конфликтом (правило %q)", action, precedence)
+			}
+		}
+	}
+	config.Conflicts = ConflictsConfig{}
+
+	batches := []requestBatch{{file: "request.xlsx", operations: []Operation{
+		testOperation(2, actionRemove, "viewer", "ivanov"),
+		testOperation(3, actionDelete, "viewer"),
+	}}}
+	if _, err := analyzeBatches(batches); err != nil {
+		t.Errorf("удаление роли после удаления из неё пользователя считается конфликтом: %v", err)
+	}
+}
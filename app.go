@@ -15,6 +15,9 @@ import (
 type App struct {
 	version       string
 	consoleLogger *log.Logger
+	results       ResultsReport
+	inputFormat   string
+	interactive   bool
 }
 
 // NewApp создает новый экземпляр приложения
@@ -33,44 +36,77 @@ func (a *App) Run(ctx context.Context) error {
 	}
 	defer logFile.Close()
 
-	exeDir, err := os.Executable()
-	if err != nil {
-		logError("Ошибка определения пути к исполняемому файлу: ", err)
+	if err := loadConfig(); err != nil {
+		logError("Ошибка загрузки настроек: %v", err)
 		return err
 	}
-	exeDir = filepath.Dir(exeDir)
 
-	files, err := findExcelFiles(exeDir)
+	if len(os.Args) > 1 {
+		return a.runCommand(ctx, os.Args[1:])
+	}
+
+	a.interactive = true
+	files, err := a.discoverFiles()
 	if err != nil {
-		logError("Ошибка поиска Excel-файлов: ", err)
 		return err
 	}
 
 	if len(files) == 0 {
-		logWarn("Не найдено Excel-файлов для обработки")
-		logInfo("Нажмите Enter для выхода...")
-		bufio.NewReader(os.Stdin).ReadString('\n')
+		logWarn("Не найдено файлов запросов для обработки")
+		a.waitForExit()
 		return nil
 	}
 
 	return a.processFiles(files)
 }
 
-// processFiles обрабатывает найденные Excel-файлы
+// discoverFiles ищет файлы запросов рядом с исполняемым файлом
+func (a *App) discoverFiles() ([]string, error) {
+	exeDir, err := executableDir()
+	if err != nil {
+		logError("Ошибка определения пути к исполняемому файлу: ", err)
+		return nil, err
+	}
+
+	files, err := findRequestFiles(exeDir, a.inputFormat)
+	if err != nil {
+		logError("Ошибка поиска файлов запросов: ", err)
+		return nil, err
+	}
+	return files, nil
+}
+
+// waitForExit ждёт нажатия Enter при запуске без аргументов, чтобы окно консоли не закрылось
+func (a *App) waitForExit() {
+	if !a.interactive {
+		return
+	}
+	logInfo("Нажмите Enter для выхода...")
+	bufio.NewReader(os.Stdin).ReadString('\n')
+}
+
+// processFiles обрабатывает найденные файлы запросов
 func (a *App) processFiles(files []string) error {
 	logInfo("Запуск Keycloak Configurator версии %s", a.version)
-	logInfo("Найдено %d Excel-файлов для обработки", len(files))
+	logInfo("Найдено %d файлов запросов для обработки", len(files))
 	logInfo("Список файлов:")
 	for i, file := range files {
 		logInfo("%2d. %s", i+1, filepath.Base(file))
 	}
 
-	hasErrors := false
-	for _, file := range files {
-		filename := filepath.Base(file)
+	batches, hasErrors := a.loadRequestFiles(files)
+	batches, err := analyzeBatches(batches)
+	if err != nil {
+		logError("Обработка не начата: %v", err)
+		a.waitForExit()
+		return err
+	}
+
+	for _, batch := range batches {
+		filename := filepath.Base(batch.file)
 		logInfo("Начинаем обработку файла: %s", filename)
 
-		if err := a.processFile(file); err != nil {
+		if err := a.processBatch(batch); err != nil {
 			logError("Ошибка обработки файла %s: %v", filename, err)
 			hasErrors = true
 		}
@@ -78,58 +114,98 @@ func (a *App) processFiles(files []string) error {
 		logInfo("Завершена обработка файла: %s", filename)
 	}
 
+	a.results.Save()
+
 	if hasErrors {
 		logWarn("ВНИМАНИЕ: Были ошибки при обработке некоторых файлов!")
 		logInfo("Проверьте файл keycloak_configurator.log для подробностей")
 	}
 
 	logInfo("Обработка всех файлов завершена")
-	logInfo("Нажмите Enter для выхода...")
-	bufio.NewReader(os.Stdin).ReadString('\n')
+	a.waitForExit()
 	return nil
 }
 
-// processFile обрабатывает один файл
-func (a *App) processFile(file string) error {
-	operations, err := readExcelFile(file)
-	if err != nil {
-		return err
+// requestBatch содержит операции, прочитанные из одного файла запросов
+type requestBatch struct {
+	file       string
+	operations []Operation
+}
+
+// loadRequestFiles читает все файлы запросов до первого изменения в Keycloak, чтобы файлы
+// неподдерживаемого или повреждённого формата были видны сразу. Такие файлы не обрабатываются
+func (a *App) loadRequestFiles(files []string) ([]requestBatch, bool) {
+	var batches []requestBatch
+	hasErrors := false
+	for _, file := range files {
+		operations, err := readRequestFile(file, a.inputFormat)
+		if err != nil {
+			logError("Файл %s не будет обработан: %v", filepath.Base(file), err)
+			hasErrors = true
+			continue
+		}
+		batches = append(batches, requestBatch{file: file, operations: operations})
 	}
+	return batches, hasErrors
+}
 
-	if len(operations) == 0 {
-		logWarn("Файл ", filepath.Base(file), " не содержит операций для обработки")
+// processBatch обрабатывает операции одного файла
+func (a *App) processBatch(batch requestBatch) error {
+	if len(batch.operations) == 0 {
+		logWarn("Файл ", filepath.Base(batch.file), " не содержит операций для обработки")
 		return nil
 	}
 
-	for i, operation := range operations {
-		if err := a.processOperation(&operation, i, len(operations)); err != nil {
+	failedGroups := make(map[string]bool)
+	for i, operation := range batch.operations {
+		if operation.blockedByLowerEnvironment(failedGroups) {
+			logWarn("%s, строка %d: %s/%s пропущен из-за ошибки на младшем окружении (%s)",
+				operation.sourceFile, operation.rowNum, operation.instance, operation.environment, optionStopBeforeProd)
+			operation.AddError("ERROR: пропущено из-за ошибки на младшем окружении строки")
+			a.results.Add(&operation)
+			continue
+		}
+		if err := a.processOperation(&operation, i, len(batch.operations)); err != nil {
 			return err
 		}
+		if operation.hasFailed() {
+			failedGroups[operation.targetGroup()] = true
+		}
 	}
 	return nil
 }
 
 // processOperation обрабатывает одну операцию
 func (a *App) processOperation(operation *Operation, index, total int) error {
-	logInfo("Обработка операции %d/%d: %s - %s",
-		index+1, total, operation.action, operation.roleName)
+	logInfo("Обработка операции %d/%d: %s/%s %s - %s%s", index+1, total, operation.instance,
+		operation.environment, operation.action, operation.roleName, operation.bundleSuffix())
 
 	bar := progressbar.Default(int64(len(operation.ldaps)))
+	defer a.results.Add(operation)
 
 	if err := operation.Authenticate(); err != nil {
 		logError("Ошибка аутентификации для операции %s: %v", operation.roleName, err)
+		operation.failed = true
 		operation.printErrors()
 		return nil
 	}
 
 	if err := operation.FindClientIdByName(); err != nil {
 		logError("Ошибка поиска клиента для операции ", operation.roleName, ": ", err)
+		operation.failed = true
 		operation.printErrors()
 		return nil
 	}
 
-	if err := operation.FindOrCreateGroupByName(); err != nil {
+	operation.resolveTemplatedNames()
+
+	findGroup := operation.FindOrCreateGroupByName
+	if operation.action == actionDelete || operation.action == actionRename {
+		findGroup = operation.FindGroupByName
+	}
+	if err := findGroup(); err != nil {
 		logError("Ошибка работы с группами для операции ", operation.roleName, ": ", err)
+		operation.failed = true
 		operation.printErrors()
 		return nil
 	}
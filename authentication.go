@@ -8,6 +8,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"os"
+	"slices"
 
 	"github.com/go-resty/resty/v2"
 )
@@ -16,8 +18,24 @@ import (
 var (
 	keycloakUser string
 	keycloakPass string
+	workbookPass string
 )
 
+// workbookPasswordEnvVar задаёт пароль зашифрованных книг запросов без пересборки
+const workbookPasswordEnvVar = "KRC_WORKBOOK_PASSWORD"
+
+// workbookPasswords возвращает пароли зашифрованных книг запросов в порядке проверки:
+// из переменной окружения, затем встроенный при сборке
+func workbookPasswords() []string {
+	var passwords []string
+	for _, password := range []string{os.Getenv(workbookPasswordEnvVar), workbookPass} {
+		if password != "" && !slices.Contains(passwords, password) {
+			passwords = append(passwords, password)
+		}
+	}
+	return passwords
+}
+
 // Session представляет структуру ответа от сервера аутентификации Keycloak
 //   - Содержит все поля, возвращаемые при успешной аутентификации
 type Session struct {
@@ -44,7 +62,13 @@ func (app *Operation) Authenticate() error {
 		return err
 	}
 
+	app.applyKnownServerPrefix()
 	res, err := app.sendAuthRequest()
+	if err == nil && res.StatusCode() == http.StatusNotFound && !app.hasLegacyPrefix() {
+		// Keycloak до 17 версии обслуживает запросы под префиксом /auth
+		app.useLegacyPrefix()
+		res, err = app.sendAuthRequest()
+	}
 	if err != nil {
 		return app.handleAuthError(err, res)
 	}
@@ -55,6 +79,7 @@ func (app *Operation) Authenticate() error {
 	}
 
 	app.configureClient(session)
+	app.detectServer()
 	return nil
 }
 
@@ -5,15 +5,31 @@ APP_NAME="KeycloakRolesConfigurator"
 VERSION="${VERSION:-1.0}"
 BUILD_DIR="build"
 
+# read_auth читает значение ключа из auth.yaml целиком, включая пробелы, и снимает кавычки
+read_auth() {
+    sed -n "s/^[[:space:]]*$1:[[:space:]]*//p" auth.yaml | head -n 1 |
+        sed -e 's/[[:space:]]*$//' -e 's/^"\(.*\)"$/\1/' -e "s/^'\(.*\)'$/\1/"
+}
+
 # Чтение учётных данных из auth.yaml
-KEYCLOAK_USER=$(grep 'admin_user' auth.yaml | awk '{print $2}' | tr -d '"')
-KEYCLOAK_PASS=$(grep 'admin_pass' auth.yaml | awk '{print $2}' | tr -d '"')
+KEYCLOAK_USER=$(read_auth admin_user)
+KEYCLOAK_PASS=$(read_auth admin_pass)
+# Пароль зашифрованных книг запросов (необязательно)
+WORKBOOK_PASS=$(read_auth workbook_pass)
 
 # Проверка наличия учётных данных
 if [ -z "$KEYCLOAK_USER" ] || [ -z "$KEYCLOAK_PASS" ]; then
     echo "Ошибка: не удалось прочитать учётные данные из auth.yaml"
     exit 1
 fi
+# Значения передаются в -ldflags в одинарных кавычках, поэтому сами одинарные кавычки не допускаются
+for value in "$KEYCLOAK_USER" "$KEYCLOAK_PASS" "$WORKBOOK_PASS"; do
+    if [[ "$value" == *"'"* ]]; then
+        echo "Ошибка: значения в auth.yaml не должны содержать одинарную кавычку"
+        exit 1
+    fi
+done
+LDFLAGS="-X main.version=$VERSION -X 'main.keycloakUser=$KEYCLOAK_USER' -X 'main.keycloakPass=$KEYCLOAK_PASS' -X 'main.workbookPass=$WORKBOOK_PASS'"
 
 # Очистка старых сборок
 echo "Очистка старых файлов сборки..."
@@ -39,13 +55,13 @@ echo "Сборка версии: $VERSION"
 # Сборка для macOS
 echo "1/2 Сборка для macOS (darwin/arm64)..."
 env GOOS=darwin GOARCH=arm64 go build \
-  -ldflags "-X main.version=$VERSION -X main.keycloakUser=$KEYCLOAK_USER -X main.keycloakPass=$KEYCLOAK_PASS" \
+  -ldflags "$LDFLAGS" \
   -o "${BUILD_DIR}/darwin/${APP_NAME}_v${VERSION}"
 
 # Сборка для Windows
 echo "2/2 Сборка для Windows (windows/amd64)..."
 env GOOS=windows GOARCH=amd64 go build \
-  -ldflags "-X main.version=$VERSION -X main.keycloakUser=$KEYCLOAK_USER -X main.keycloakPass=$KEYCLOAK_PASS" \
+  -ldflags "$LDFLAGS" \
   -o "${BUILD_DIR}/windows/${APP_NAME}_v${VERSION}.exe"
 
 # Проверка успешности сборки
@@ -0,0 +1,113 @@
+// bundles.go разворачивает наборы ролей
+//   - Набор задаётся в config.yaml: имя набора -> инстанс ("Employee" или "Employee/Prod") -> пары клиент/роль
+//   - В колонке Role name набор указывается как "@bundle:accountant", колонка Client ID не заполняется
+//   - Строка с набором разворачивается в операцию на каждую пару клиент/роль инстанса строки
+//   - Имя набора выводится в лог и в колонку Bundle отчёта о результатах
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// bundlePrefix начинает ссылку на набор ролей в колонке Role name
+const bundlePrefix = "@bundle:"
+
+// BundleRole содержит одну клиентскую роль набора
+type BundleRole struct {
+	Client string `yaml:"client"` // clientId клиента Keycloak
+	Role   string `yaml:"role"`   // Имя клиентской роли
+}
+
+// parseBundleReference возвращает имя набора, если в колонке Role name указан набор ролей
+func parseBundleReference(roleName string) (string, bool) {
+	name, found := strings.CutPrefix(strings.TrimSpace(roleName), bundlePrefix)
+	return strings.TrimSpace(name), found
+}
+
+// actionSupportsBundles сообщает, можно ли указать набор ролей для действия
+func actionSupportsBundles(action string) bool {
+	return action == actionCreate || action == actionAssociate || action == actionRemove
+}
+
+// bundle возвращает роли набора для пары инстанс/окружение, затем для типа инстанса
+func (c *Config) bundle(name, instance, environment string) ([]BundleRole, error) {
+	instances, ok := c.Bundles[name]
+	if !ok {
+		names := make([]string, 0, len(c.Bundles))
+		for known := range c.Bundles {
+			names = append(names, known)
+		}
+		slices.Sort(names)
+		return nil, fmt.Errorf("набор ролей %s не задан в config.yaml. Заданные наборы: %v", name, names)
+	}
+	if roles, ok := instances[instance+"/"+environment]; ok {
+		return roles, nil
+	}
+	if roles, ok := instances[instance]; ok {
+		return roles, nil
+	}
+	return nil, fmt.Errorf("в наборе ролей %s нет ролей для %s/%s", name, instance, environment)
+}
+
+// validateBundles проверяет наборы ролей из настроек
+func validateBundles(bundles map[string]map[string][]BundleRole) error {
+	for name, instances := range bundles {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("bundles: пустое имя набора")
+		}
+		for key, roles := range instances {
+			instance, environment, hasEnvironment := strings.Cut(key, "/")
+			if !slices.Contains(instanceOrder, instance) ||
+				(hasEnvironment && !slices.Contains(environmentOrder, environment)) {
+				return fmt.Errorf("набор %s: неизвестный инстанс %s. Ожидается тип (%s) или пара тип/окружение",
+					name, key, strings.Join(instanceOrder, ", "))
+			}
+			if len(roles) == 0 {
+				return fmt.Errorf("набор %s: для %s не указаны роли", name, key)
+			}
+			for _, role := range roles {
+				if strings.TrimSpace(role.Client) == "" || strings.TrimSpace(role.Role) == "" {
+					return fmt.Errorf("набор %s: для %s у каждой роли нужны client и role", name, key)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// expandBundle заменяет операцию с набором ролей операциями для каждой роли набора
+func expandBundle(operation Operation, name string) ([]Operation, error) {
+	if !actionSupportsBundles(operation.action) {
+		return nil, fmt.Errorf("наборы ролей поддерживаются только для действий %s, %s, %s",
+			actionCreate, actionAssociate, actionRemove)
+	}
+	roles, err := config.bundle(name, operation.instance, operation.environment)
+	if err != nil {
+		return nil, err
+	}
+
+	operations := make([]Operation, 0, len(roles))
+	for _, role := range roles {
+		expanded := newOperation(operation.instance, operation.environment)
+		expanded.ClientIdName = role.Client
+		expanded.action = operation.action
+		expanded.roleName = role.Role
+		expanded.bundle = name
+		expanded.ldaps = operation.ldaps
+		expanded.ldapsString = operation.ldapsString
+		expanded.options = operation.options
+		expanded.rowNum = operation.rowNum
+		operations = append(operations, expanded)
+	}
+	return operations, nil
+}
+
+// bundleSuffix возвращает пометку набора ролей для сообщений в логе
+func (o *Operation) bundleSuffix() string {
+	if o.bundle == "" {
+		return ""
+	}
+	return " (набор " + o.bundle + ")"
+}
@@ -11,6 +11,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/go-resty/resty/v2"
 )
@@ -26,6 +27,7 @@ type Client struct {
 type Group struct {
 	ID        string  `json:"id"`        // Уникальный ID группы
 	Name      string  `json:"name"`      // Название группы
+	Path      string  `json:"path"`      // Полный путь группы, например "/Roles/client/role"
 	SubGroups []Group `json:"subGroups"` // Рекурсивная структура подгрупп
 }
 
@@ -38,9 +40,33 @@ const (
 	rolesGroupName        = "Roles"
 )
 
+// fetchAllPages постранично запрашивает список объектов, пока Keycloak не вернёт неполную страницу
+func fetchAllPages[T any](fetch func(first, max int) (*resty.Response, error)) ([]T, error) {
+	var all []T
+	for first := 0; ; first += defaultMaxResults {
+		res, err := fetch(first, defaultMaxResults)
+		if err != nil {
+			return nil, err
+		}
+		if res.StatusCode() != http.StatusOK {
+			return nil, fmt.Errorf("HTTP %d: %s", res.StatusCode(), res.String())
+		}
+
+		var page []T
+		if err := json.Unmarshal(res.Body(), &page); err != nil {
+			return nil, err
+		}
+		all = append(all, page...)
+
+		if len(page) < defaultMaxResults {
+			return all, nil
+		}
+	}
+}
+
 // FindClientIdByName ищет клиента в Keycloak по имени и сохраняет его ID в Operation
 func (app *Operation) FindClientIdByName() error {
-	if cachedID, exists := clientIdCache[app.ClientIdName]; exists {
+	if cachedID, exists := clientIdCache[app.clientCacheKey()]; exists {
 		app.clientId = cachedID
 		return nil
 	}
@@ -58,42 +84,40 @@ func (app *Operation) FindClientIdByName() error {
 	return nil
 }
 
-// fetchClients выполняет запрос к API Keycloak для поиска клиентов
+// clientCacheKey возвращает ключ кэша клиента: одно имя клиента может быть в разных инстансах
+func (app *Operation) clientCacheKey() string {
+	return app.client.BaseURL + "|" + app.realm + "|" + app.ClientIdName
+}
+
+// fetchClients выполняет запрос к API Keycloak для поиска клиентов, постранично читая результаты
 func (app *Operation) fetchClients() ([]Client, error) {
-	res, err := app.client.R().
-		SetPathParam("instance", app.realm).
-		SetQueryParams(app.buildClientSearchParams()).
-		Get(clientsEndpoint)
+	var res *resty.Response
+	clients, err := fetchAllPages[Client](func(first, max int) (*resty.Response, error) {
+		var err error
+		res, err = app.client.R().
+			SetPathParam("instance", app.realm).
+			SetQueryParams(app.buildClientSearchParams(first, max)).
+			Get(clientsEndpoint)
+		return res, err
+	})
 
 	if err != nil {
 		app.logClientSearchError(res)
 		return nil, err
 	}
-
-	return app.parseClientsResponse(res)
+	return clients, nil
 }
 
-// buildClientSearchParams создает параметры для поиска клиента
-func (app *Operation) buildClientSearchParams() map[string]string {
+// buildClientSearchParams создает параметры для точного поиска клиента по clientId
+func (app *Operation) buildClientSearchParams(first, max int) map[string]string {
 	return map[string]string{
 		"clientId": app.ClientIdName,
-		"first":    "0",
-		"max":      fmt.Sprintf("%d", defaultMaxResults),
-		"search":   "true",
+		"first":    strconv.Itoa(first),
+		"max":      strconv.Itoa(max),
+		"search":   "false",
 	}
 }
 
-// parseClientsResponse парсит ответ от API Keycloak
-func (app *Operation) parseClientsResponse(res *resty.Response) ([]Client, error) {
-	var clients []Client
-	if err := json.Unmarshal(res.Body(), &clients); err != nil {
-		app.AddError(fmt.Sprintf("Ошибка парсинга клиентов: %s. Пропускаем LDAP: %s",
-			err, app.ldapsString))
-		return nil, err
-	}
-	return clients, nil
-}
-
 // validateClientSearchResults проверяет результаты поиска клиента
 func (app *Operation) validateClientSearchResults(clients []Client) error {
 	switch len(clients) {
@@ -112,14 +136,18 @@ func (app *Operation) validateClientSearchResults(clients []Client) error {
 
 // cacheAndSetClientID сохраняет ID клиента в кэш и структуру Operation
 func (app *Operation) cacheAndSetClientID(clientID string) {
-	clientIdCache[app.ClientIdName] = clientID
+	clientIdCache[app.clientCacheKey()] = clientID
 	app.clientId = clientID
 }
 
 // logClientSearchError логирует ошибку поиска клиента
 func (app *Operation) logClientSearchError(res *resty.Response) {
+	status := 0
+	if res != nil {
+		status = res.StatusCode()
+	}
 	app.AddError(fmt.Sprintf("Ошибка поиска клиента: %d. Пропускаем LDAP: %s",
-		res.StatusCode(), app.ldapsString))
+		status, app.ldapsString))
 }
 
 // FindOrCreateGroupByName ищет или создает группу для ролей
@@ -137,74 +165,99 @@ func (app *Operation) FindOrCreateGroupByName() error {
 	return app.createClientSubgroup(rolesGroup.ID)
 }
 
-// findRolesGroup ищет родительскую группу "Roles"
+// FindGroupByName ищет подгруппу клиента в группе Roles, не создавая её: удаление и переименование
+// роли не должны ничего создавать. Если подгруппы нет, parentGroupId остаётся пустым
+func (app *Operation) FindGroupByName() error {
+	rolesGroup, err := app.findRolesGroup()
+	if err != nil {
+		return err
+	}
+	app.parentGroupId = app.findClientSubgroup(rolesGroup)
+	return nil
+}
+
+// findRolesGroup ищет родительскую группу "Roles" среди групп верхнего уровня
 func (app *Operation) findRolesGroup() (*Group, error) {
-	res, err := app.client.R().
-		SetPathParam("realm", app.realm).
-		SetQueryParams(app.buildRolesGroupSearchParams()).
-		Get(groupsEndpoint)
+	var res *resty.Response
+	groups, err := fetchAllPages[Group](func(first, max int) (*resty.Response, error) {
+		var err error
+		res, err = app.client.R().
+			SetPathParam("realm", app.realm).
+			SetQueryParams(app.buildRolesGroupSearchParams(first, max)).
+			Get(groupsEndpoint)
+		return res, err
+	})
 
 	if err != nil {
 		app.logGroupSearchError(res)
 		return nil, err
 	}
 
-	return app.parseRolesGroupResponse(res)
+	for i := range groups {
+		if groups[i].Name == rolesGroupName {
+			return &groups[i], nil
+		}
+	}
+	app.logGroupSearchError(res)
+	return nil, fmt.Errorf("group %s not found", rolesGroupName)
 }
 
-// buildRolesGroupSearchParams создает параметры для поиска группы "Roles"
-func (app *Operation) buildRolesGroupSearchParams() map[string]string {
+// buildRolesGroupSearchParams создает параметры для точного поиска группы "Roles"
+func (app *Operation) buildRolesGroupSearchParams(first, max int) map[string]string {
 	return map[string]string{
 		"exact":  "true",
 		"search": rolesGroupName,
-		"global": "true",
-		"max":    "21",
-	}
-}
-
-// parseRolesGroupResponse парсит ответ с информацией о группе "Roles"
-func (app *Operation) parseRolesGroupResponse(res *resty.Response) (*Group, error) {
-	var groups []Group
-	if err := json.Unmarshal(res.Body(), &groups); err != nil || len(groups) == 0 {
-		app.logGroupSearchError(res)
-		return nil, fmt.Errorf("failed to parse roles group")
+		"first":  strconv.Itoa(first),
+		"max":    strconv.Itoa(max),
 	}
-	return &groups[0], nil
 }
 
 // findClientSubgroup ищет подгруппу клиента в группе "Roles"
 func (app *Operation) findClientSubgroup(rolesGroup *Group) string {
-	subgroups, err := app.getGroupSubgroups(rolesGroup.ID)
+	id, err := app.findChildGroup(rolesGroup.ID, app.ClientIdName)
 	if err != nil {
 		return ""
 	}
+	return id
+}
+
+// findChildGroup ищет дочернюю группу по точному имени, постранично читая результаты поиска
+func (app *Operation) findChildGroup(parentID, name string) (string, error) {
+	subgroups, err := app.getGroupSubgroups(parentID, name)
+	if err != nil {
+		return "", err
+	}
 
 	for _, subgroup := range subgroups {
-		if subgroup.Name == app.ClientIdName {
-			return subgroup.ID
+		if subgroup.Name == name {
+			return subgroup.ID, nil
 		}
 	}
-	return ""
+	return "", nil
 }
 
-// getGroupSubgroups получает подгруппы указанной группы
-func (app *Operation) getGroupSubgroups(groupID string) ([]Group, error) {
-	res, err := app.client.R().
-		SetPathParam("realm", app.realm).
-		SetPathParam("groupId", groupID).
-		SetQueryParams(map[string]string{
-			"first": "0",
-			"max":   "250",
-		}).
-		Get(groupChildrenEndpoint)
-
-	if err != nil {
-		return nil, err
+// getGroupSubgroups получает все подгруппы указанной группы; непустой search включает точный поиск по имени
+func (app *Operation) getGroupSubgroups(groupID, search string) ([]Group, error) {
+	if !app.serverInfo().ChildrenEndpoint {
+		return app.getEmbeddedSubgroups(groupID, search)
 	}
 
-	var subgroups []Group
-	err = json.Unmarshal(res.Body(), &subgroups)
-	return subgroups, err
+	return fetchAllPages[Group](func(first, max int) (*resty.Response, error) {
+		req := app.client.R().
+			SetPathParam("realm", app.realm).
+			SetPathParam("groupId", groupID).
+			SetQueryParams(map[string]string{
+				"first": strconv.Itoa(first),
+				"max":   strconv.Itoa(max),
+			})
+		if search != "" {
+			req.SetQueryParams(map[string]string{
+				"search": search,
+				"exact":  "true",
+			})
+		}
+		return req.Get(groupChildrenEndpoint)
+	})
 }
 
 // createClientSubgroup создает новую подгруппу для клиента
@@ -213,8 +266,8 @@ func (app *Operation) createClientSubgroup(parentGroupID string) error {
 		SetBody(Role{Name: app.ClientIdName}).
 		SetHeader("Content-Type", "application/json;charset=UTF-8").
 		SetPathParams(map[string]string{
-			"instance": app.realm,
-			"group":    parentGroupID,
+			"realm":   app.realm,
+			"groupId": parentGroupID,
 		}).
 		Post(groupChildrenEndpoint)
 
@@ -234,8 +287,12 @@ func (app *Operation) createClientSubgroup(parentGroupID string) error {
 
 // logGroupSearchError логирует ошибку поиска группы
 func (app *Operation) logGroupSearchError(res *resty.Response) {
+	status := 0
+	if res != nil {
+		status = res.StatusCode()
+	}
 	app.AddError(fmt.Sprintf("Не удается получить группу ролей из keycloak: %d. Пропускаем LDAP:%s",
-		res.StatusCode(), app.ldapsString))
+		status, app.ldapsString))
 }
 
 // logGroupCreationError логирует ошибку создания группы
@@ -0,0 +1,142 @@
+// columns.go сопоставляет колонки листа запросов по названиям заголовков
+//   - Названия сравниваются без учёта регистра и лишних пробелов
+//   - Синонимы на русском и английском, дополнительные - в config.yaml
+//   - Отсутствующие обязательные и повторяющиеся заголовки считаются ошибкой
+//   - Неизвестные колонки передаются в отчёт о результатах как есть
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Колонки листа запросов в порядке, в котором их ожидает createOperationsFromRow
+const (
+	columnType        = "type"
+	columnEnvironment = "environment"
+	columnAction      = "action"
+	columnClient      = "client"
+	columnRole        = "role"
+	columnLogins      = "logins"
+	columnOptions     = "options"
+)
+
+// requestColumns перечисляет колонки в порядке позиций строки операции
+var requestColumns = []string{columnType, columnEnvironment, columnAction, columnClient, columnRole, columnLogins, columnOptions}
+
+// optionalColumns содержит колонки, которых может не быть в заголовке.
+// Логины нужны не всем действиям, их отсутствие проверяется по строкам
+var optionalColumns = map[string]bool{columnLogins: true, columnOptions: true}
+
+// defaultColumnSynonyms содержит названия заголовков, распознаваемые без настройки
+var defaultColumnSynonyms = map[string][]string{
+	columnType:        {"Keycloak type", "Type", "Instance", "Тип", "Тип Keycloak", "Инстанс"},
+	columnEnvironment: {"Keycloak environment", "Environment", "Env", "Окружение", "Среда"},
+	columnAction:      {"Action", "Действие"},
+	columnClient:      {"Client ID", "Client", "Клиент", "ID клиента"},
+	columnRole:        {"Role name", "Role", "Роль", "Имя роли"},
+	columnLogins:      {"User logins", "User login", "Logins", "Login", "LDAPs", "LDAP", "Логины", "Логин", "Пользователи"},
+	columnOptions:     {"Options", "Опции", "Параметры"},
+}
+
+// ColumnLayout содержит позиции колонок листа запросов
+type ColumnLayout struct {
+	positions  map[string]int // Позиция колонки по её ключу
+	extras     []string       // Заголовки дополнительных колонок
+	extraPos   []int          // Позиции дополнительных колонок
+	byPosition bool           // Колонки взяты по позициям шаблона, заголовки не распознаны
+}
+
+// ExtraValue содержит значение дополнительной колонки строки
+type ExtraValue struct {
+	Header string
+	Value  string
+}
+
+// newColumnLayout определяет позиции колонок по строке заголовков
+func newColumnLayout(header []string) (*ColumnLayout, error) {
+	synonyms := columnSynonyms()
+	layout := &ColumnLayout{positions: make(map[string]int)}
+
+	for i, title := range header {
+		normalized := normalizeHeader(title)
+		if normalized == "" {
+			continue
+		}
+
+		key, known := synonyms[normalized]
+		if !known {
+			layout.extras = append(layout.extras, strings.TrimSpace(title))
+			layout.extraPos = append(layout.extraPos, i)
+			continue
+		}
+		if previous, duplicated := layout.positions[key]; duplicated {
+			return nil, fmt.Errorf("колонка %s повторяется в заголовке: '%s' и '%s'",
+				key, header[previous], title)
+		}
+		layout.positions[key] = i
+	}
+
+	var missing []string
+	for _, key := range requestColumns {
+		if _, ok := layout.positions[key]; !ok && !optionalColumns[key] {
+			missing = append(missing, fmt.Sprintf("%s (%s)", key, strings.Join(defaultColumnSynonyms[key], ", ")))
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("в заголовке нет обязательных колонок: %s", strings.Join(missing, "; "))
+	}
+	return layout, nil
+}
+
+// columnSynonyms объединяет стандартные и настроенные названия заголовков
+func columnSynonyms() map[string]string {
+	synonyms := make(map[string]string)
+	for key, names := range defaultColumnSynonyms {
+		for _, name := range names {
+			synonyms[normalizeHeader(name)] = key
+		}
+	}
+	for key, names := range config.Columns {
+		for _, name := range names {
+			synonyms[normalizeHeader(name)] = key
+		}
+	}
+	return synonyms
+}
+
+// normalizeHeader приводит заголовок к виду для сравнения
+func normalizeHeader(title string) string {
+	return strings.ToLower(strings.Join(strings.Fields(title), " "))
+}
+
+// mapRow переставляет ячейки строки в порядок requestColumns и собирает дополнительные колонки
+func (l *ColumnLayout) mapRow(row []string) ([]string, []ExtraValue) {
+	mapped := make([]string, len(requestColumns))
+	for i, key := range requestColumns {
+		if pos, ok := l.positions[key]; ok && pos < len(row) {
+			mapped[i] = row[pos]
+		}
+	}
+
+	extras := make([]ExtraValue, 0, len(l.extras))
+	for i, header := range l.extras {
+		value := ""
+		if pos := l.extraPos[i]; pos < len(row) {
+			value = row[pos]
+		}
+		extras = append(extras, ExtraValue{Header: header, Value: value})
+	}
+	return mapped, extras
+}
+
+// validateColumnsConfig проверяет ключи синонимов колонок в настройках
+func validateColumnsConfig(columns map[string][]string) error {
+	for key := range columns {
+		if _, ok := defaultColumnSynonyms[key]; !ok {
+			return fmt.Errorf("неизвестная колонка %s в columns. Допустимые: %s",
+				key, strings.Join(requestColumns, ", "))
+		}
+	}
+	return nil
+}
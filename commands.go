@@ -0,0 +1,146 @@
+// commands.go содержит разбор команд командной строки
+//   - Без аргументов программа обрабатывает файлы запросов рядом с исполняемым файлом
+//   - С аргументами выполняет указанную команду
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// Command описывает команду командной строки
+type Command struct {
+	Name        string
+	Description string
+	Run         func(a *App, ctx context.Context, args []string) error
+}
+
+// commands содержит все доступные команды
+var commands = []Command{
+	{Name: "run", Description: "обработать файлы запросов (как запуск без аргументов)", Run: (*App).runRequests},
+	{Name: "offboard", Description: "удалить пользователей из всех ролей во всех инстансах", Run: (*App).runOffboard},
+	{Name: "members", Description: "показать всех участников клиентской роли", Run: (*App).runMembers},
+	{Name: "validate", Description: "проверить файлы запросов на живом инстансе без изменений", Run: (*App).runValidate},
+	{Name: "whois", Description: "показать доступы пользователей во всех инстансах", Run: (*App).runWhois},
+	{Name: "template", Description: "создать шаблон файла запросов с выпадающими списками", Run: (*App).runTemplate},
+}
+
+// runCommand находит и выполняет команду по имени
+func (a *App) runCommand(ctx context.Context, args []string) error {
+	for _, cmd := range commands {
+		if cmd.Name == args[0] {
+			err := cmd.Run(a, ctx, args[1:])
+			if errors.Is(err, flag.ErrHelp) {
+				return nil
+			}
+			return err
+		}
+	}
+
+	printUsage()
+	return fmt.Errorf("неизвестная команда: %s", args[0])
+}
+
+// runRequests выполняет команду run: обработку указанных или найденных файлов запросов
+func (a *App) runRequests(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	fs.StringVar(&a.inputFormat, "input-format", "", "формат файлов запросов: xlsx, xls, ods, csv, yaml, json (по умолчанию - по расширению)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Использование: run [параметры] [файл1 ...]")
+		fmt.Fprintln(fs.Output(), "Без файлов обрабатываются файлы запросов рядом с исполняемым файлом.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	files := fs.Args()
+	if len(files) == 0 {
+		var err error
+		if files, err = a.discoverFiles(); err != nil {
+			return err
+		}
+	}
+	if len(files) == 0 {
+		logWarn("Не найдено файлов запросов для обработки")
+		return nil
+	}
+	return a.processFiles(files)
+}
+
+// printUsage выводит список команд
+func printUsage() {
+	fmt.Println("Использование: KeycloakRolesConfigurator [команда] [параметры]")
+	fmt.Println("Без команды обрабатываются файлы запросов рядом с исполняемым файлом.")
+	fmt.Println("Команды:")
+	for _, cmd := range commands {
+		fmt.Printf("  %-12s %s\n", cmd.Name, cmd.Description)
+	}
+}
+
+// targetFlags содержит общие параметры выбора инстансов и окружений
+type targetFlags struct {
+	types        string
+	environments string
+}
+
+// register регистрирует параметры выбора инстансов в наборе флагов
+func (t *targetFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&t.types, "types", strings.ReplaceAll(validTypes, "|", ","), "типы Keycloak через запятую")
+	fs.StringVar(&t.environments, "envs", strings.ReplaceAll(validEnvironments, "|", ","), "окружения через запятую")
+}
+
+// targets возвращает операции для всех выбранных пар инстанс/окружение
+func (t *targetFlags) targets() ([]Operation, error) {
+	types, err := parseTargetList(t.types, validTypes)
+	if err != nil {
+		return nil, err
+	}
+	environments, err := parseTargetList(t.environments, validEnvironments)
+	if err != nil {
+		return nil, err
+	}
+
+	var operations []Operation
+	for _, instance := range types {
+		for _, environment := range environments {
+			operations = append(operations, newOperation(instance, environment))
+		}
+	}
+	return operations, nil
+}
+
+// parseTargetList разбирает список через запятую и проверяет значения по списку допустимых
+func parseTargetList(list, valid string) ([]string, error) {
+	allowed := strings.Split(valid, "|")
+	var result []string
+	for _, value := range parseLDAPs(list) {
+		found := false
+		for _, a := range allowed {
+			if strings.EqualFold(a, value) {
+				result = append(result, a)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("недопустимое значение %s. Допустимые: %s", value, valid)
+		}
+	}
+	return result, nil
+}
+
+// reportFlags содержит общие параметры вывода отчёта
+type reportFlags struct {
+	format string
+	output string
+}
+
+// register регистрирует параметры отчёта в наборе флагов
+func (r *reportFlags) register(fs *flag.FlagSet, defaultFormat string) {
+	fs.StringVar(&r.format, "format", defaultFormat, "формат отчёта: table, csv, json, xlsx")
+	fs.StringVar(&r.output, "output", "", "файл отчёта (по умолчанию - консоль или директория reports)")
+}
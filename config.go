@@ -0,0 +1,150 @@
+// config.go загружает необязательный файл настроек config.yaml
+//   - Файл ищется рядом с исполняемым файлом или по пути из KRC_CONFIG
+//   - Без файла используются значения по умолчанию
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	configFileName = "config.yaml"
+	configEnvVar   = "KRC_CONFIG"
+)
+
+// Config содержит настройки инструмента
+type Config struct {
+	Instances map[string]InstanceConfig          `yaml:"instances"` // Настройки по типу ("Employee") или паре ("Employee/Prod")
+	Normalize NormalizeConfig                    `yaml:"normalize"` // Нормализация логинов перед поиском
+	Columns   map[string][]string                `yaml:"columns"`   // Дополнительные названия заголовков по ключу колонки
+	Workbook  WorkbookConfig                     `yaml:"workbook"`  // Чтение книг запросов
+	Conflicts ConflictsConfig                    `yaml:"conflicts"` // Разрешение конфликтов между строками запросов
+	Bundles   map[string]map[string][]BundleRole `yaml:"bundles"`   // Наборы ролей: имя -> инстанс -> пары клиент/роль
+	Naming    map[string]NamingPolicy            `yaml:"naming"`    // Политика именования ролей по типу или паре тип/окружение
+}
+
+// WorkbookConfig содержит настройки чтения книг запросов
+type WorkbookConfig struct {
+	Sheets string `yaml:"sheets"` // Шаблон имён листов запросов, например "Request*"; по умолчанию "Request"
+}
+
+// InstanceConfig содержит настройки одного инстанса Keycloak
+type InstanceConfig struct {
+	Identifier          string `yaml:"identifier"`           // Стратегия поиска пользователя: username, email, attribute, auto
+	IdentifierAttribute string `yaml:"identifier_attribute"` // Атрибут пользователя для стратегии attribute, например employeeNumber
+	DisabledUsers       string `yaml:"disabled_users"`       // Политика для отключённых пользователей: skip, warn, allow
+	UnverifiedUsers     string `yaml:"unverified_users"`     // Политика для пользователей с неподтверждённым e-mail: skip, warn, allow
+	FederationImport    string `yaml:"federation_import"`    // Импорт ненайденных пользователей из федерации: search, sync
+}
+
+// config содержит загруженные настройки
+var config = &Config{}
+
+// loadConfig загружает настройки из файла, если он существует
+func loadConfig() error {
+	path := os.Getenv(configEnvVar)
+	if path == "" {
+		exeDir, err := executableDir()
+		if err != nil {
+			return err
+		}
+		path = filepath.Join(exeDir, configFileName)
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка чтения настроек %s: %w", path, err)
+	}
+
+	var loaded Config
+	if err := yaml.Unmarshal(data, &loaded); err != nil {
+		return fmt.Errorf("ошибка разбора настроек %s: %w", path, err)
+	}
+	if err := loaded.validate(); err != nil {
+		return fmt.Errorf("ошибка в настройках %s: %w", path, err)
+	}
+
+	config = &loaded
+	logInfo("Загружены настройки из %s", path)
+	return loadAliases(filepath.Dir(path))
+}
+
+// validate проверяет значения настроек
+func (c *Config) validate() error {
+	if err := validateColumnsConfig(c.Columns); err != nil {
+		return err
+	}
+	if _, err := path.Match(c.Workbook.Sheets, ""); err != nil {
+		return fmt.Errorf("workbook: некорректный шаблон листов %s: %w", c.Workbook.Sheets, err)
+	}
+	if err := validateBundles(c.Bundles); err != nil {
+		return err
+	}
+	if err := validateNaming(c.Naming); err != nil {
+		return err
+	}
+	if precedence := c.Conflicts.Precedence; precedence != "" && !isPrecedence(precedence) {
+		return fmt.Errorf("conflicts: неизвестное правило приоритета %s. Допустимые: %s, %s",
+			precedence, precedenceAdd, precedenceRemove)
+	}
+	for name, instance := range c.Instances {
+		if instance.Identifier != "" && !isIdentifierStrategy(instance.Identifier) {
+			return fmt.Errorf("инстанс %s: неизвестная стратегия identifier %s", name, instance.Identifier)
+		}
+		if instance.Identifier == identifierAttribute && instance.IdentifierAttribute == "" {
+			return fmt.Errorf("инстанс %s: для стратегии %s нужен identifier_attribute", name, identifierAttribute)
+		}
+		if mode := instance.FederationImport; mode != "" && mode != federationImportSearch && mode != federationImportSync {
+			return fmt.Errorf("инстанс %s: неизвестный режим federation_import %s. Допустимые: %s, %s",
+				name, mode, federationImportSearch, federationImportSync)
+		}
+		for _, policy := range []string{instance.DisabledUsers, instance.UnverifiedUsers} {
+			if policy != "" && !isUserPolicy(policy) {
+				return fmt.Errorf("инстанс %s: неизвестная политика %s. Допустимые: %s, %s, %s",
+					name, policy, policySkip, policyWarn, policyAllow)
+			}
+		}
+	}
+	return nil
+}
+
+// instance возвращает настройки для пары инстанс/окружение, затем для типа инстанса
+func (c *Config) instance(instance, environment string) InstanceConfig {
+	if cfg, ok := c.Instances[instance+"/"+environment]; ok {
+		return cfg
+	}
+	return c.Instances[instance]
+}
+
+// disabledPolicy возвращает политику для отключённых пользователей
+func (c InstanceConfig) disabledPolicy() string {
+	if c.DisabledUsers == "" {
+		return defaultDisabledPolicy
+	}
+	return c.DisabledUsers
+}
+
+// unverifiedPolicy возвращает политику для пользователей с неподтверждённым e-mail
+func (c InstanceConfig) unverifiedPolicy() string {
+	if c.UnverifiedUsers == "" {
+		return defaultUnverifiedPolicy
+	}
+	return c.UnverifiedUsers
+}
+
+// sheetPattern возвращает шаблон имён листов запросов
+func (c WorkbookConfig) sheetPattern() string {
+	if c.Sheets == "" {
+		return excelSheetName
+	}
+	return c.Sheets
+}
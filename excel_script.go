@@ -10,7 +10,8 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"regexp"
+	"path"
+	"slices"
 	"strings"
 
 	"github.com/go-resty/resty/v2"
@@ -21,69 +22,161 @@ import (
 const (
 	validTypes        = "Employee|Partner|Customer"
 	validEnvironments = "Prod|Dev|Test"
-	validActions      = "Create new role and add users to this role|Associate users with role|Remove users from role"
-	excelSheetName    = "Request"
-	minColumnsCount   = 6
+	validActions      = actionCreate + "|" + actionAssociate + "|" + actionRemove + "|" + actionDelete + "|" + actionRename + "|" +
+		actionCopyMembers + "|" + actionMoveMembers
+	excelSheetName  = "Request"
+	minColumnsCount = 6
+	maxColumnsCount = 7
 )
 
+// Действия, которые можно указать в колонке Action
+const (
+	actionCreate      = "Create new role and add users to this role"
+	actionAssociate   = "Associate users with role"
+	actionRemove      = "Remove users from role"
+	actionDelete      = "Delete role"
+	actionRename      = "Rename role"
+	actionCopyMembers = "Copy members"
+	actionMoveMembers = "Move members"
+)
+
+// rolePairSeparator разделяет исходную и целевую роль в колонке Role name, например "old -> new"
+const rolePairSeparator = "->"
+
+// Опции, которые можно указать в необязательной колонке Options (через запятую)
+const (
+	optionConfirm        = "confirm"          // подтверждает удаление роли, в которой есть участники
+	optionCreateTarget   = "create-target"    // создаёт целевую роль при копировании/переносе участников
+	optionImportUsers    = "import-users"     // импортирует ненайденных пользователей из федерации
+	optionStopBeforeProd = "stop-before-prod" // не выполнять строку на Prod после ошибки на младшем окружении
+)
+
+// allMembersMarker в колонке логинов означает всех участников исходной роли
+const allMembersMarker = "*"
+
 // ExcelConfig содержит конфигурацию для работы с Excel
 type ExcelConfig struct {
-	FilePath   string
-	SheetName  string
-	HeaderRows int
-	MinColumns int
+	FilePath     string
+	SheetPattern string
+	HeaderRows   int
+	MinColumns   int
 }
 
-// readExcelFile читает и парсит Excel-файл, преобразуя его в массив Operation
-func readExcelFile(filePath string) ([]Operation, error) {
-	config := ExcelConfig{
-		FilePath:   filePath,
-		SheetName:  excelSheetName,
-		HeaderRows: 1,
-		MinColumns: minColumnsCount,
+// newExcelConfig создает конфигурацию чтения листов запросов
+func newExcelConfig(filePath string) ExcelConfig {
+	return ExcelConfig{
+		FilePath:     filePath,
+		SheetPattern: config.Workbook.sheetPattern(),
+		HeaderRows:   1,
+		MinColumns:   minColumnsCount,
 	}
+}
 
-	rows, err := readExcelRows(config)
+// readExcelSheets читает из Excel файла все листы и версию шаблона. На листах запросов формула-ссылка
+// на колонку другого листа (например, =Users!A:A) заменяется текстом ссылки вместо вычисленного значения
+func readExcelSheets(config ExcelConfig) ([]workbookSheet, templateStamp, error) {
+	f, err := openExcelFile(config.FilePath)
 	if err != nil {
-		return nil, fmt.Errorf("%v", err)
+		return nil, templateStamp{}, err
 	}
+	defer closeExcelFile(f)
 
-	return processExcelRows(rows), nil
-}
+	stamp, err := readTemplateStamp(f)
+	if err != nil {
+		return nil, stamp, err
+	}
 
-// readExcelRows читает данные из Excel файла
-func readExcelRows(config ExcelConfig) ([][]string, error) {
-	f, err := excelize.OpenFile(config.FilePath)
+	names := f.GetSheetList()
+	requestSheets, err := matchRequestSheets(config, names)
 	if err != nil {
-		return nil, fmt.Errorf("ошибка открытия файла: %w", err)
+		return nil, stamp, err
 	}
-	defer closeExcelFile(f)
 
-	// Получаем список всех листов
-	sheets := f.GetSheetList()
-	sheetExists := false
-	for _, sheet := range sheets {
-		if sheet == config.SheetName {
-			sheetExists = true
-			break
+	sheets := make([]workbookSheet, 0, len(names))
+	for _, name := range names {
+		rows, err := f.GetRows(name)
+		if err != nil {
+			return nil, stamp, fmt.Errorf("ошибка чтения листа %s: %w", name, err)
 		}
+		if slices.Contains(requestSheets, name) {
+			keepReferenceFormulas(f, name, rows)
+		}
+		sheets = append(sheets, workbookSheet{Name: name, Rows: rows})
 	}
+	return sheets, stamp, nil
+}
 
-	if !sheetExists {
-		return nil, fmt.Errorf("лист '%s' не найден. Доступные листы: %v",
-			config.SheetName, sheets)
+// keepReferenceFormulas заменяет значения ячеек с формулой-ссылкой на колонку листа текстом формулы
+func keepReferenceFormulas(f *excelize.File, sheet string, rows [][]string) {
+	if len(rows) == 0 {
+		return
 	}
+	width := len(rows[0])
+	for i := range rows {
+		for j := 0; j < width; j++ {
+			cell, err := excelize.CoordinatesToCellName(j+1, i+1)
+			if err != nil {
+				continue
+			}
+			formula, err := f.GetCellFormula(sheet, cell)
+			if err != nil || formula == "" || !isLoginsReferenceFormula(formula) {
+				continue
+			}
+			rows[i] = padRow(rows[i], j+1)
+			rows[i][j] = "=" + strings.TrimPrefix(formula, "=")
+		}
+	}
+}
 
-	rows, err := f.GetRows(config.SheetName)
-	if err != nil {
-		return nil, fmt.Errorf("ошибка чтения листа %s: %w", config.SheetName, err)
+// openExcelFile открывает книгу Excel; зашифрованная книга открывается паролем
+// из переменной окружения или паролем, заданным при сборке
+func openExcelFile(filePath string) (*excelize.File, error) {
+	if !isEncryptedWorkbook(filePath) {
+		f, err := excelize.OpenFile(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка открытия файла: %w", err)
+		}
+		return f, nil
 	}
 
-	if len(rows) <= config.HeaderRows {
-		return nil, fmt.Errorf("файл не содержит данных для обработки")
+	passwords := workbookPasswords()
+	if len(passwords) == 0 {
+		return nil, fmt.Errorf("книга защищена паролем, а пароль не задан: укажите его в переменной окружения %s "+
+			"или в workbook_pass файла auth.yaml перед сборкой", workbookPasswordEnvVar)
+	}
+	for _, password := range passwords {
+		if f, err := excelize.OpenFile(filePath, excelize.Options{Password: password}); err == nil {
+			return f, nil
+		}
+	}
+	return nil, fmt.Errorf("книга защищена паролем, заданный пароль не подходит")
+}
+
+// matchRequestSheets возвращает имена листов, подходящих под шаблон листов запросов, в порядке книги
+func matchRequestSheets(config ExcelConfig, sheets []string) ([]string, error) {
+	var matched []string
+	for _, sheet := range sheets {
+		if ok, _ := path.Match(config.SheetPattern, sheet); ok {
+			matched = append(matched, sheet)
+		}
+	}
+	if len(matched) == 0 {
+		return nil, sheetNotFoundError(config, sheets)
 	}
+	return matched, nil
+}
+
+// sheetNotFoundError сообщает об отсутствии листа запросов и перечисляет листы книги
+func sheetNotFoundError(config ExcelConfig, sheets []string) error {
+	return fmt.Errorf("лист '%s' не найден. Доступные листы: %v", config.SheetPattern, sheets)
+}
 
-	return rows[config.HeaderRows:], nil
+// splitSheetRows отделяет строку заголовков листа от строк данных
+func splitSheetRows(config ExcelConfig, rows [][]string) ([]string, [][]string, error) {
+	if len(rows) <= config.HeaderRows {
+		return nil, nil, fmt.Errorf("файл не содержит данных для обработки")
+	}
+	return rows[config.HeaderRows-1], rows[config.HeaderRows:], nil
 }
 
 // closeExcelFile безопасно закрывает файл Excel
@@ -93,42 +186,99 @@ func closeExcelFile(f *excelize.File) {
 	}
 }
 
-// processExcelRows обрабатывает строки Excel и преобразует их в операции
-func processExcelRows(rows [][]string) []Operation {
+// processRequestRows обрабатывает строки таблицы запросов и преобразует их в операции
+func processRequestRows(layout *ColumnLayout, table RequestTable, filePath string) []Operation {
 	var operations []Operation
 
-	for i, row := range rows {
-		operation, err := createOperationFromRow(row, i+2)
+	for i, row := range table.Rows {
+		rowNum := i + table.FirstRow
+		mapped, extras := layout.mapRow(row)
+		mapped, err := table.resolveLogins(mapped, rowNum)
+		if err != nil {
+			log.Printf("%s, строка %d: %v - пропущена", table.Name, rowNum, err)
+			continue
+		}
+		rowOperations, err := createOperationsFromRow(mapped, rowNum)
 		if err != nil {
-			log.Printf("Строка %d: %v - пропущена", i+2, err)
+			log.Printf("%s, строка %d: %v - пропущена", table.Name, rowNum, err)
 			continue
 		}
-		operations = append(operations, operation)
+		for _, operation := range rowOperations {
+			operation.sourceFile = tableSource(filePath, table)
+			operation.extras = extras
+			operations = append(operations, operation)
+		}
 	}
 
 	return operations
 }
 
-// createOperationFromRow создает Operation из строки Excel
-func createOperationFromRow(row []string, rowNum int) (Operation, error) {
+// createOperationsFromRow создает операции из строки Excel: по одной на каждую пару инстанс/окружение строки
+func createOperationsFromRow(row []string, rowNum int) ([]Operation, error) {
+	row = padRow(row, maxColumnsCount)
 	if err := validateExcelRow(row, rowNum); err != nil {
-		return Operation{}, err
-	}
-
-	baseURL, realm := getURLAndRealm(row[0], row[1])
-	ldaps := parseLDAPs(row[5])
-
-	return Operation{
-		client:       createHTTPClient(baseURL),
-		ClientIdName: row[3],
-		realm:        realm,
-		action:       row[2],
-		roleName:     row[4],
-		ldaps:        ldaps,
-		ldapsString:  row[5],
-		errors:       make(map[int]string),
-		errorCounter: 0,
-	}, nil
+		return nil, err
+	}
+	targets, err := rowTargets(row[0], row[1])
+	if err != nil {
+		return nil, fmt.Errorf("WARN - %w", err)
+	}
+
+	bundle, isBundle := parseBundleReference(row[4])
+	if isBundle && strings.TrimSpace(row[3]) != "" {
+		logWarn("Строка %d: для набора ролей %s колонка Client ID не используется: %s", rowNum, bundle, row[3])
+	}
+
+	roleName, targetRoleName := strings.TrimSpace(row[4]), ""
+	if actionRequiresRolePair(row[2]) {
+		roleName, targetRoleName = splitRolePair(row[4])
+	}
+	logins, duplicates := uniqueLogins(splitLogins(row[5]))
+	if len(duplicates) > 0 {
+		logWarn("Строка %d: повторяющиеся логины учтены один раз: %s", rowNum, strings.Join(duplicates, ", "))
+	}
+
+	operations := make([]Operation, 0, len(targets))
+	for _, target := range targets {
+		operation := newOperation(target[0], target[1])
+		operation.ClientIdName = row[3]
+		operation.action = row[2]
+		operation.roleName = roleName
+		operation.targetRoleName = targetRoleName
+		operation.ldaps = logins
+		operation.ldapsString = row[5]
+		operation.options = parseOptions(row[6])
+		operation.rowNum = rowNum
+		if !isBundle {
+			operations = append(operations, operation)
+			continue
+		}
+
+		expanded, err := expandBundle(operation, bundle)
+		if err != nil {
+			return nil, fmt.Errorf("WARN - строка %d: %w", rowNum, err)
+		}
+		logInfo("Строка %d: набор ролей %s для %s/%s - ролей %d", rowNum, bundle, operation.instance,
+			operation.environment, len(expanded))
+		operations = append(operations, expanded...)
+	}
+	for i := range operations {
+		if err := operations[i].applyNamingPolicy(); err != nil {
+			return nil, fmt.Errorf("WARN - строка %d: %s/%s: %w", rowNum, operations[i].instance, operations[i].environment, err)
+		}
+	}
+	if len(operations) > 1 {
+		logInfo("Строка %d выполняется для %d целей", rowNum, len(operations))
+	}
+	return operations, nil
+}
+
+// padRow дополняет строку пустыми ячейками: excelize отбрасывает пустые ячейки в конце строки
+func padRow(row []string, size int) []string {
+	for len(row) < size {
+		row = append(row, "")
+	}
+	return row
 }
 
 // validateExcelRow проверяет корректность строки из Excel-файла
@@ -151,6 +301,12 @@ func validateExcelRow(row []string, rowNum int) error {
 	}
 
 	for _, f := range requiredFields {
+		if f.index == 5 && !actionRequiresLogins(row[2]) {
+			continue
+		}
+		if _, isBundle := parseBundleReference(row[4]); f.index == 3 && isBundle {
+			continue
+		}
 		if strings.TrimSpace(row[f.index]) == "" {
 			errMsg := fmt.Sprintf("строка %d: %s не может быть пустым", rowNum, f.name)
 			logWarn(errMsg)
@@ -158,26 +314,48 @@ func validateExcelRow(row []string, rowNum int) error {
 		}
 	}
 
-	// Валидация значений
-	validators := []struct {
-		index  int
-		regex  string
-		errMsg string
-	}{
-		{0, validTypes, "неверный тип Keycloak: %s. Допустимые: %s"},
-		{1, validEnvironments, "неверное окружение: %s. Допустимые: %s"},
-		{2, validActions, "неверное действие: %s. Допустимые: %s"},
+	// Действие должно совпадать с допустимым целиком; тип и окружение могут содержать список, он проверяется в rowTargets
+	if !slices.Contains(strings.Split(validActions, "|"), row[2]) {
+		return fmt.Errorf("WARN - неверное действие: %s. Допустимые: %s", row[2], validActions)
 	}
 
-	for _, v := range validators {
-		if !regexp.MustCompile(v.regex).MatchString(row[v.index]) {
-			return fmt.Errorf("WARN - "+v.errMsg, row[v.index], v.regex)
+	if !actionRequiresRolePair(row[2]) && strings.Contains(row[4], rolePairSeparator) {
+		return fmt.Errorf("WARN - пара ролей '%s' допустима только для действий %s, %s, %s: %s",
+			rolePairSeparator, actionRename, actionCopyMembers, actionMoveMembers, row[4])
+	}
+	if actionRequiresRolePair(row[2]) {
+		source, target := splitRolePair(row[4])
+		if target == "" {
+			return fmt.Errorf("WARN - для действия %s укажите роли в формате 'исходная %s целевая': %s",
+				row[2], rolePairSeparator, row[4])
+		}
+		if source == target {
+			return fmt.Errorf("WARN - для действия %s исходная и целевая роль должны различаться: %s", row[2], row[4])
 		}
 	}
 
 	return nil
 }
 
+// actionRequiresLogins сообщает, обязательна ли колонка с логинами для действия
+func actionRequiresLogins(action string) bool {
+	return !actionRequiresRolePair(action) && action != actionDelete
+}
+
+// actionRequiresRolePair сообщает, ожидает ли действие пару ролей в колонке Role name
+func actionRequiresRolePair(action string) bool {
+	return action == actionRename || action == actionCopyMembers || action == actionMoveMembers
+}
+
+// splitRolePair разбивает колонку Role name на исходную и целевую роль
+func splitRolePair(roleName string) (string, string) {
+	source, target, found := strings.Cut(roleName, rolePairSeparator)
+	if !found {
+		return strings.TrimSpace(roleName), ""
+	}
+	return strings.TrimSpace(source), strings.TrimSpace(target)
+}
+
 // createHTTPClient создает и настраивает HTTP-клиент
 func createHTTPClient(baseURL string) *resty.Client {
 	return resty.New().
@@ -185,21 +363,20 @@ func createHTTPClient(baseURL string) *resty.Client {
 		SetHeader("Content-Type", "Application/x-www-form-urlencoded")
 }
 
-// parseLDAPs разбивает строку LDAP-групп на массив
+// parseLDAPs разбивает строку логинов на список без повторов
 func parseLDAPs(ldaps string) []string {
-	if ldaps == "" {
-		return nil
-	}
-
-	splitStr := strings.Split(ldaps, ",")
-	result := make([]string, 0, len(splitStr))
+	logins, _ := uniqueLogins(splitLogins(ldaps))
+	return logins
+}
 
-	for _, str := range splitStr {
-		if trimmed := strings.TrimSpace(str); trimmed != "" {
+// parseOptions разбивает строку опций на список в нижнем регистре
+func parseOptions(options string) []string {
+	var result []string
+	for _, option := range strings.Split(options, ",") {
+		if trimmed := strings.ToLower(strings.TrimSpace(option)); trimmed != "" {
 			result = append(result, trimmed)
 		}
 	}
-
 	return result
 }
 
@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+func TestValidateExcelRowAction(t *testing.T) {
+	tests := map[string]bool{
+		actionAssociate:          true,
+		actionDelete:             true,
+		actionDelete + " xyz":    false,
+		"Rename role copy":       false,
+		"delete role":            false,
+		"Please " + actionDelete: false,
+	}
+	for action, valid := range tests {
+		row := []string{"Employee", "Dev", action, "crm", "viewer", "ivanov", ""}
+		err := validateExcelRow(row, 2)
+		if valid && err != nil {
+			t.Errorf("действие %q отклонено: %v", action, err)
+		}
+		if !valid && err == nil {
+			t.Errorf("действие %q принято", action)
+		}
+	}
+}
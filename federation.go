@@ -0,0 +1,144 @@
+// federation.go импортирует пользователей из федерации (LDAP) по требованию
+//   - Пользователи, ни разу не входившие в систему, ещё не импортированы в Keycloak
+//   - Неточный поиск заставляет провайдер федерации найти и импортировать пользователя
+//   - При необходимости запускается синхронизация изменённых пользователей
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+const (
+	componentsEndpoint      = "/admin/realms/{instance}/components"
+	userStorageSyncEndpoint = "/admin/realms/{instance}/user-storage/{providerId}/sync"
+	userStorageProviderType = "org.keycloak.storage.UserStorageProvider"
+	changedUsersSyncAction  = "triggerChangedUsersSync"
+	federationImportSearch  = "search" // только неточный поиск через провайдер федерации
+	federationImportSync    = "sync"   // поиск, затем синхронизация изменённых пользователей
+)
+
+// Component представляет компонент realm, например провайдер федерации пользователей
+type Component struct {
+	ID         string              `json:"id"`         // UUID компонента
+	Name       string              `json:"name"`       // Отображаемое имя
+	ProviderID string              `json:"providerId"` // Тип провайдера, например "ldap"
+	Config     map[string][]string `json:"config"`     // Настройки компонента
+}
+
+// syncedProviders хранит провайдеры, синхронизированные в текущем запуске, чтобы не повторять синхронизацию
+var syncedProviders = make(map[string]bool)
+
+// federationImportMode возвращает режим импорта: из настроек инстанса или search для опции import-users
+func (app *Operation) federationImportMode() string {
+	if app.readOnly {
+		return ""
+	}
+	if mode := app.instanceConfig().FederationImport; mode != "" {
+		return mode
+	}
+	if app.hasOption(optionImportUsers) {
+		return federationImportSearch
+	}
+	return ""
+}
+
+// importFromFederation пытается импортировать пользователя из федерации и повторяет поиск
+func (app *Operation) importFromFederation(ldap string, params map[string]string, value string) []UserResponse {
+	mode := app.federationImportMode()
+	if mode == "" {
+		return nil
+	}
+
+	providers := app.getFederationProviders()
+	if len(providers) == 0 {
+		return nil
+	}
+
+	// Неточный поиск передаётся провайдерам федерации, найденные в каталоге пользователи импортируются
+	app.searchCandidates(map[string]string{"search": value})
+	if users := app.retryUserSearch(ldap, params); len(users) > 0 {
+		app.addOutcome(ldap, "импортирован", "поиском через федерацию")
+		return users
+	}
+
+	if mode != federationImportSync {
+		return nil
+	}
+	for _, provider := range providers {
+		app.syncChangedUsers(provider)
+	}
+	if users := app.retryUserSearch(ldap, params); len(users) > 0 {
+		app.addOutcome(ldap, "импортирован", "синхронизацией федерации")
+		return users
+	}
+	return nil
+}
+
+// retryUserSearch повторяет точный поиск пользователя после импорта
+func (app *Operation) retryUserSearch(ldap string, params map[string]string) []UserResponse {
+	if err := app.checkRateLimit(ldap); err != nil {
+		return nil
+	}
+	resp, err := app.searchUser(ldap, params)
+	if err != nil {
+		return nil
+	}
+	users, err := app.parseUserResponse(resp, ldap)
+	if err != nil {
+		return nil
+	}
+	return users
+}
+
+// getFederationProviders возвращает включённые провайдеры федерации пользователей realm
+func (app *Operation) getFederationProviders() []Component {
+	res, err := app.client.R().
+		SetPathParam("instance", app.realm).
+		SetQueryParam("type", userStorageProviderType).
+		Get(componentsEndpoint)
+
+	if err != nil || res.StatusCode() != http.StatusOK {
+		app.AddError(fmt.Sprintf("Не удалось получить провайдеры федерации realm %s: %d", app.realm, res.StatusCode()))
+		return nil
+	}
+
+	var components []Component
+	if err := json.Unmarshal(res.Body(), &components); err != nil {
+		app.AddError(fmt.Sprintf("Ошибка парсинга провайдеров федерации: %v", err))
+		return nil
+	}
+
+	var enabled []Component
+	for _, component := range components {
+		if values := component.Config["enabled"]; len(values) > 0 && values[0] == "false" {
+			continue
+		}
+		enabled = append(enabled, component)
+	}
+	return enabled
+}
+
+// syncChangedUsers запускает синхронизацию изменённых пользователей провайдера один раз за запуск
+func (app *Operation) syncChangedUsers(provider Component) {
+	key := app.serverKey() + "|" + app.realm + "|" + provider.ID
+	if syncedProviders[key] {
+		return
+	}
+	syncedProviders[key] = true
+
+	logInfo("Синхронизация изменённых пользователей провайдера %s (%s)", provider.Name, app.realm)
+	res, err := app.client.R().
+		SetPathParams(map[string]string{
+			"instance":   app.realm,
+			"providerId": provider.ID,
+		}).
+		SetQueryParam("action", changedUsersSyncAction).
+		Post(userStorageSyncEndpoint)
+
+	if err != nil || res.StatusCode() != http.StatusOK {
+		app.AddError(fmt.Sprintf("Ошибка синхронизации провайдера федерации %s: %s, статус: %d",
+			provider.Name, res.String(), res.StatusCode()))
+	}
+}
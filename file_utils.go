@@ -2,6 +2,7 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
@@ -22,12 +23,12 @@ var (
 )
 
 func initLogger() error {
-	exePath, err := os.Executable()
+	exeDir, err := executableDir()
 	if err != nil {
 		return fmt.Errorf("failed to get executable path: %w", err)
 	}
 
-	logPath := filepath.Join(filepath.Dir(exePath), "keycloak_configurator.log")
+	logPath := filepath.Join(exeDir, "keycloak_configurator.log")
 	logFile, err = os.OpenFile(logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		return fmt.Errorf("failed to open log file: %w", err)
@@ -41,6 +42,39 @@ func initLogger() error {
 	return nil
 }
 
+// executableDir возвращает директорию исполняемого файла
+func executableDir() (string, error) {
+	exePath, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Dir(exePath), nil
+}
+
+// writeJSONFile сохраняет значение в JSON-файл в поддиректории рядом с исполняемым файлом
+func writeJSONFile(subdir, name string, v interface{}) (string, error) {
+	exeDir, err := executableDir()
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(exeDir, subdir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("ошибка создания директории %s: %w", dir, err)
+	}
+
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("ошибка записи файла %s: %w", path, err)
+	}
+	return path, nil
+}
+
 type colorWriter struct {
 	w io.Writer
 }
@@ -68,9 +102,10 @@ func logError(format string, v ...interface{}) {
 	logger.Printf("ERROR - "+format, v...)
 }
 
-// findExcelFiles ищет все Excel-файлы в указанной директории
-func findExcelFiles(dir string) ([]string, error) {
-	var excelFiles []string
+// findRequestFiles ищет в указанной директории файлы запросов: Excel, CSV, YAML и JSON.
+// Непустой format ограничивает поиск файлами с этим расширением
+func findRequestFiles(dir, format string) ([]string, error) {
+	var requestFiles []string
 
 	files, err := os.ReadDir(dir)
 	if err != nil {
@@ -78,14 +113,14 @@ func findExcelFiles(dir string) ([]string, error) {
 	}
 
 	for _, file := range files {
-		if !file.IsDir() {
-			ext := strings.ToLower(filepath.Ext(file.Name()))
-			if ext == ".xlsx" || ext == ".xls" {
-				fullPath := filepath.Join(dir, file.Name())
-				excelFiles = append(excelFiles, fullPath)
-			}
+		if file.IsDir() || !isRequestFile(file.Name()) {
+			continue
+		}
+		if format != "" && requestFormat(file.Name(), "") != strings.ToLower(format) {
+			continue
 		}
+		requestFiles = append(requestFiles, filepath.Join(dir, file.Name()))
 	}
 
-	return excelFiles, nil
+	return requestFiles, nil
 }
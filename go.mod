@@ -4,15 +4,16 @@ go 1.22.0
 
 require (
 	github.com/go-resty/resty/v2 v2.12.0
+	github.com/richardlehane/mscfb v1.0.4
 	github.com/schollz/progressbar/v3 v3.14.2
 	github.com/xuri/excelize/v2 v2.8.1
 	golang.org/x/time v0.5.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/mitchellh/colorstring v0.0.0-20190213212951-d06e56a500db // indirect
 	github.com/mohae/deepcopy v0.0.0-20170929034955-c48cc78d4826 // indirect
-	github.com/richardlehane/mscfb v1.0.4 // indirect
 	github.com/richardlehane/msoleps v1.0.3 // indirect
 	github.com/rivo/uniseg v0.4.7 // indirect
 	github.com/xuri/efp v0.0.0-20231025114914-d1ff6096ae53 // indirect
@@ -0,0 +1,182 @@
+// logins.go разбирает ячейку логинов
+//   - Логины разделяются запятой, точкой с запятой, переводом строки или пробелами
+//   - Повторяющиеся логины учитываются один раз
+//   - Ссылка "=Users!A:A" или "@sheet:Users" берёт список логинов с другого листа книги
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// sheetReferencePrefix начинает ссылку на лист со списком логинов: "@sheet:Users" или "@sheet:Users!B"
+const sheetReferencePrefix = "@sheet:"
+
+// rangeReferencePattern описывает ссылку на колонку листа в стиле Excel: Users!A:A, 'Список'!B2:B50, Users!A2
+var rangeReferencePattern = regexp.MustCompile(
+	`^=?\s*(?:'((?:[^']|'')+)'|([^'!=]+))!\$?([A-Za-z]{1,3})\$?(\d*)(?::\$?([A-Za-z]{1,3})\$?(\d*))?\s*$`)
+
+// loginsReference описывает диапазон одной колонки листа со списком логинов
+type loginsReference struct {
+	sheet    string
+	column   int // Номер колонки с 1
+	firstRow int // Первая строка с 1; 0 - с начала листа
+	lastRow  int // Последняя строка с 1; 0 - до конца листа
+}
+
+// splitLogins разбивает ячейку логинов по запятым, точкам с запятой, переводам строк и пробелам.
+// Префикс стратегии, отделённый пробелом ("email: ivanov@corp.ru"), остаётся с логином
+func splitLogins(cell string) []string {
+	fields := strings.FieldsFunc(cell, func(r rune) bool {
+		return r == ',' || r == ';' || unicode.IsSpace(r)
+	})
+
+	var result []string
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+		if strings.HasSuffix(field, ":") && i+1 < len(fields) {
+			if strategy, _ := splitIdentifierPrefix(field); strategy != "" {
+				field += fields[i+1]
+				i++
+			}
+		}
+		result = append(result, field)
+	}
+	return result
+}
+
+// uniqueLogins убирает повторяющиеся логины без учёта регистра и возвращает список повторов
+func uniqueLogins(logins []string) ([]string, []string) {
+	seen := make(map[string]bool, len(logins))
+	unique := make([]string, 0, len(logins))
+	var duplicates []string
+	for _, login := range logins {
+		key := strings.ToLower(login)
+		if seen[key] {
+			duplicates = append(duplicates, login)
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, login)
+	}
+	return unique, duplicates
+}
+
+// parseLoginsReference распознаёт ссылку на лист со списком логинов.
+// Возвращает false, если ячейка содержит обычный список логинов
+func parseLoginsReference(cell string) (loginsReference, bool, error) {
+	cell = strings.TrimSpace(cell)
+	if rest, found := strings.CutPrefix(cell, sheetReferencePrefix); found {
+		sheet, column, hasColumn := strings.Cut(rest, "!")
+		ref := loginsReference{sheet: strings.TrimSpace(sheet), column: 1}
+		if hasColumn {
+			number, err := excelize.ColumnNameToNumber(strings.TrimSpace(column))
+			if err != nil {
+				return ref, true, fmt.Errorf("некорректная колонка в ссылке %s", cell)
+			}
+			ref.column = number
+		}
+		if ref.sheet == "" {
+			return ref, true, fmt.Errorf("в ссылке %s не указан лист", cell)
+		}
+		return ref, true, nil
+	}
+
+	if !strings.HasPrefix(cell, "=") {
+		return loginsReference{}, false, nil
+	}
+	match := rangeReferencePattern.FindStringSubmatch(cell)
+	if match == nil {
+		return loginsReference{}, true, fmt.Errorf("ссылка %s не распознана, ожидается вида =Users!A:A или %sUsers",
+			cell, sheetReferencePrefix)
+	}
+
+	sheet := strings.ReplaceAll(match[1], "''", "'")
+	if sheet == "" {
+		sheet = strings.TrimSpace(match[2])
+	}
+	lastColumn, lastRow := match[5], match[6]
+	if lastColumn == "" {
+		lastColumn, lastRow = match[3], match[4]
+	}
+	if !strings.EqualFold(match[3], lastColumn) {
+		return loginsReference{}, true, fmt.Errorf("ссылка %s должна указывать на одну колонку", cell)
+	}
+
+	column, err := excelize.ColumnNameToNumber(match[3])
+	if err != nil {
+		return loginsReference{}, true, fmt.Errorf("некорректная колонка в ссылке %s", cell)
+	}
+	ref := loginsReference{sheet: sheet, column: column}
+	ref.firstRow, _ = strconv.Atoi(match[4])
+	ref.lastRow, _ = strconv.Atoi(lastRow)
+	return ref, true, nil
+}
+
+// isLoginsReferenceFormula проверяет, что формула ячейки - ссылка на колонку другого листа
+func isLoginsReferenceFormula(formula string) bool {
+	return rangeReferencePattern.MatchString(formula)
+}
+
+// resolveLogins подставляет в ячейку логинов список с листа, на который она ссылается
+func (t RequestTable) resolveLogins(row []string, rowNum int) ([]string, error) {
+	index := slices.Index(requestColumns, columnLogins)
+	ref, isReference, err := parseLoginsReference(row[index])
+	if !isReference || err != nil {
+		return row, err
+	}
+
+	logins, err := t.referencedLogins(ref)
+	if err != nil {
+		return row, err
+	}
+	logInfo("%s, строка %d: логины взяты с листа %s: %d", t.Name, rowNum, ref.sheet, len(logins))
+
+	resolved := slices.Clone(row)
+	resolved[index] = strings.Join(logins, ", ")
+	return resolved, nil
+}
+
+// referencedLogins читает логины из колонки листа книги. Если диапазон начинается с начала листа,
+// первая ячейка с заголовком колонки логинов ("Логины", "LDAPs" и т.п.) пропускается
+func (t RequestTable) referencedLogins(ref loginsReference) ([]string, error) {
+	if t.Sheets == nil {
+		return nil, fmt.Errorf("ссылки на листы со списком логинов поддерживаются только в книгах Excel и LibreOffice")
+	}
+	index := slices.IndexFunc(t.Sheets, func(sheet workbookSheet) bool {
+		return strings.EqualFold(sheet.Name, ref.sheet)
+	})
+	if index < 0 {
+		return nil, fmt.Errorf("лист %s со списком логинов не найден", ref.sheet)
+	}
+
+	rows := t.Sheets[index].Rows
+	first, last := max(ref.firstRow, 1), len(rows)
+	if ref.lastRow > 0 {
+		last = min(ref.lastRow, len(rows))
+	}
+
+	synonyms := columnSynonyms()
+	var logins []string
+	for rowNum := first; rowNum <= last; rowNum++ {
+		row := rows[rowNum-1]
+		if ref.column > len(row) {
+			continue
+		}
+		value := row[ref.column-1]
+		if ref.firstRow == 0 && len(logins) == 0 && synonyms[normalizeHeader(value)] == columnLogins {
+			continue
+		}
+		logins = append(logins, splitLogins(value)...)
+	}
+	if len(logins) == 0 {
+		return nil, fmt.Errorf("список логинов на листе %s пуст", ref.sheet)
+	}
+	return logins, nil
+}
@@ -0,0 +1,203 @@
+// members.go предоставляет функционал для работы с участниками групп ролей
+//   - Постраничное чтение участников подгрупп и пользователей ролей
+//   - Копирование и перенос участников между ролями
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/schollz/progressbar/v3"
+)
+
+// Итоги обработки пользователя при добавлении, удалении, копировании и переносе участников
+const (
+	outcomeAdded    = "добавлен"
+	outcomeMoved    = "перенесён"
+	outcomeRemoved  = "удалён"
+	outcomePresent  = "уже в целевой роли"
+	outcomeFailed   = "ошибка"
+	outcomeNotFound = "не участник исходной роли"
+)
+
+const (
+	groupMembersEndpoint = "/admin/realms/{instance}/groups/{groupId}/members"
+	roleUsersEndpoint    = "/admin/realms/{instance}/clients/{clientId}/roles/{role}/users"
+)
+
+// Member представляет участника группы в Keycloak
+type Member struct {
+	ID       string `json:"id"`       // Внутренний UUID пользователя
+	Username string `json:"username"` // Логин пользователя
+	Email    string `json:"email"`    // Адрес электронной почты
+}
+
+// getGroupMembers возвращает всех участников группы, постранично читая список
+func (app *Operation) getGroupMembers(groupId string) ([]Member, error) {
+	members, err := fetchAllPages[Member](func(first, max int) (*resty.Response, error) {
+		return app.client.R().
+			SetPathParams(map[string]string{
+				"instance": app.realm,
+				"groupId":  groupId,
+			}).
+			SetQueryParams(app.setBriefRepresentation(map[string]string{
+				"first": strconv.Itoa(first),
+				"max":   strconv.Itoa(max),
+			}, true)).
+			Get(groupMembersEndpoint)
+	})
+	if err != nil {
+		app.AddError(fmt.Sprintf("Ошибка получения участников группы %s: %v", groupId, err))
+		return nil, err
+	}
+	return members, nil
+}
+
+// getRoleUsers возвращает всех пользователей, которым клиентская роль назначена напрямую
+func (app *Operation) getRoleUsers(roleName string) ([]Member, error) {
+	users, err := fetchAllPages[Member](func(first, max int) (*resty.Response, error) {
+		return app.client.R().
+			SetPathParams(map[string]string{
+				"instance": app.realm,
+				"clientId": app.clientId,
+				"role":     roleName,
+			}).
+			SetQueryParams(map[string]string{
+				"first": strconv.Itoa(first),
+				"max":   strconv.Itoa(max),
+			}).
+			Get(roleUsersEndpoint)
+	})
+	if err != nil {
+		app.AddError(fmt.Sprintf("Ошибка получения пользователей роли %s: %v", roleName, err))
+		return nil, err
+	}
+	return users, nil
+}
+
+// memberUsernames возвращает логины участников
+func memberUsernames(members []Member) []string {
+	usernames := make([]string, 0, len(members))
+	for _, member := range members {
+		usernames = append(usernames, member.Username)
+	}
+	return usernames
+}
+
+// transferMembers копирует или переносит участников подгруппы исходной роли в целевую роль
+func (app *Operation) transferMembers(sourceGroupId string, bar *progressbar.ProgressBar) {
+	if app.roleName == app.targetRoleName {
+		app.fail(fmt.Sprintf("ERROR: исходная и целевая роль совпадают: %s", app.roleName))
+		return
+	}
+	targetGroupId := app.findOrCreateTargetRole()
+	if targetGroupId == "" {
+		app.failed = true
+		return
+	}
+	if targetGroupId == sourceGroupId {
+		app.fail(fmt.Sprintf("ERROR: подгруппы исходной роли %s и целевой роли %s совпадают",
+			app.roleName, app.targetRoleName))
+		return
+	}
+
+	members, err := app.getGroupMembers(sourceGroupId)
+	if err != nil {
+		app.failed = true
+		return
+	}
+	members = app.selectMembers(members)
+
+	targetMembers, err := app.getGroupMembers(targetGroupId)
+	if err != nil {
+		app.failed = true
+		return
+	}
+	present := make(map[string]bool, len(targetMembers))
+	for _, member := range targetMembers {
+		present[member.ID] = true
+	}
+
+	move := app.action == actionMoveMembers
+	bar.ChangeMax(len(members))
+	processed := 0
+	for _, member := range members {
+		_ = bar.Add(1)
+
+		status := outcomeAdded
+		if present[member.ID] {
+			status = outcomePresent
+		} else if !app.addMember(member.ID, targetGroupId) {
+			app.addOutcome(member.Username, outcomeFailed, "не удалось добавить в "+app.targetRoleName)
+			continue
+		}
+
+		if move {
+			if !app.removeMember(member.ID, sourceGroupId) {
+				app.addOutcome(member.Username, outcomeFailed, "добавлен в "+app.targetRoleName+", но не удалён из "+app.roleName)
+				continue
+			}
+			if status == outcomeAdded {
+				status = outcomeMoved
+			}
+		}
+		app.addOutcome(member.Username, status, app.roleName+" -> "+app.targetRoleName)
+		processed++
+	}
+
+	logInfo("%s: обработано %d из %d участников роли %s", app.action, processed, len(members), app.roleName)
+}
+
+// findOrCreateTargetRole находит целевую роль и её подгруппу, при опции create-target создаёт недостающее
+func (app *Operation) findOrCreateTargetRole() string {
+	roleId := app.findRole(app.targetRoleName, false)
+	groupId := app.getSubGroupByName(app.targetRoleName)
+	if roleId != "" && groupId != "" {
+		return groupId
+	}
+
+	if !app.hasOption(optionCreateTarget) {
+		app.AddError(fmt.Sprintf("Целевая роль %s не существует. Укажите опцию '%s', чтобы создать её",
+			app.targetRoleName, optionCreateTarget))
+		return ""
+	}
+
+	if roleId == "" {
+		app.createRole(app.targetRoleName)
+		roleId = app.findRole(app.targetRoleName, true)
+	}
+	if groupId == "" {
+		groupId = app.createSubGroup(app.targetRoleName)
+	}
+	if roleId == "" || groupId == "" {
+		return ""
+	}
+
+	app.assignRole(app.targetRoleName, roleId, groupId)
+	return groupId
+}
+
+// selectMembers оставляет участников, перечисленных в колонке логинов; "*" или пустая колонка - все участники
+func (app *Operation) selectMembers(members []Member) []Member {
+	if len(app.ldaps) == 0 || (len(app.ldaps) == 1 && app.ldaps[0] == allMembersMarker) {
+		return members
+	}
+
+	byLogin := make(map[string]Member, len(members))
+	for _, member := range members {
+		byLogin[strings.ToLower(member.Username)] = member
+	}
+
+	selected := make([]Member, 0, len(app.ldaps))
+	for _, ldap := range app.ldaps {
+		member, ok := byLogin[strings.ToLower(ldap)]
+		if !ok {
+			app.addOutcome(ldap, outcomeNotFound, app.roleName)
+			continue
+		}
+		selected = append(selected, member)
+	}
+	return selected
+}
@@ -0,0 +1,317 @@
+// members_cmd.go реализует команду members
+//   - Участники подгруппы Roles/<client>/<role>
+//   - Пользователи с прямым назначением роли
+//   - Пользователи, получившие роль через составные роли, в том числе через подгруппы групп с такой ролью
+//   - Цепочки через составные роли других клиентов не учитываются, об этом сообщается в выводе команды
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+)
+
+const (
+	clientRolesEndpoint      = "/admin/realms/{instance}/clients/{clientId}/roles"
+	realmRolesEndpoint       = "/admin/realms/{instance}/roles"
+	realmRoleUsersEndpoint   = "/admin/realms/{instance}/roles/{role}/users"
+	realmRoleGroupsEndpoint  = "/admin/realms/{instance}/roles/{role}/groups"
+	clientRoleGroupsEndpoint = "/admin/realms/{instance}/clients/{clientId}/roles/{role}/groups"
+	roleCompositesEndpoint   = "/admin/realms/{instance}/roles-by-id/{roleId}/composites"
+)
+
+// RoleDetails содержит представление роли, достаточное для поиска составных ролей
+type RoleDetails struct {
+	ID          string `json:"id"`          // Внутренний UUID роли
+	Name        string `json:"name"`        // Имя роли
+	Composite   bool   `json:"composite"`   // Является ли роль составной
+	ClientRole  bool   `json:"clientRole"`  // Клиентская ли это роль
+	ContainerID string `json:"containerId"` // UUID клиента или realm, которому принадлежит роль
+}
+
+// roleMember содержит участника роли и источники, через которые он её получил
+type roleMember struct {
+	member  Member
+	sources []string
+}
+
+// runMembers выполняет команду members
+func (a *App) runMembers(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("members", flag.ContinueOnError)
+	var report reportFlags
+	report.register(fs, formatTable)
+	instance := fs.String("type", "", "тип Keycloak: "+validTypes)
+	environment := fs.String("env", "", "окружение: "+validEnvironments)
+	clientName := fs.String("client", "", "имя клиента")
+	roleName := fs.String("role", "", "имя клиентской роли")
+	direct := fs.Bool("direct", false, "включить пользователей с прямым назначением роли")
+	composite := fs.Bool("composite", false, "включить пользователей, получивших роль через составные роли")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Использование: members -type Employee -env Prod -client my-client -role my-role [параметры]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := validateReportFormat(report.format); err != nil {
+		return err
+	}
+	if *clientName == "" || *roleName == "" {
+		fs.Usage()
+		return fmt.Errorf("не указаны клиент или роль")
+	}
+
+	types, err := parseTargetList(*instance, validTypes)
+	if err != nil || len(types) != 1 {
+		return fmt.Errorf("укажите один тип Keycloak: %s", validTypes)
+	}
+	environments, err := parseTargetList(*environment, validEnvironments)
+	if err != nil || len(environments) != 1 {
+		return fmt.Errorf("укажите одно окружение: %s", validEnvironments)
+	}
+
+	operation := newOperation(types[0], environments[0])
+	operation.ClientIdName = *clientName
+	operation.roleName = *roleName
+
+	members, err := operation.collectRoleMembers(*direct, *composite)
+	operation.printErrors()
+	if err != nil {
+		return err
+	}
+
+	result := Report{Headers: []string{"Login", "Email", "ID", "Source"}}
+	for _, m := range members {
+		result.AddRow(m.member.Username, m.member.Email, m.member.ID, strings.Join(m.sources, "; "))
+	}
+
+	if report.format == formatExcel && report.output == "" {
+		if report.output, err = defaultReportPath("members", report.format); err != nil {
+			return err
+		}
+	}
+	if err := result.Write(report.format, report.output); err != nil {
+		return err
+	}
+	logInfo("Участников роли %s клиента %s: %d", *roleName, *clientName, len(members))
+	return nil
+}
+
+// collectRoleMembers собирает участников роли из подгруппы и, по запросу, из прямых и составных назначений
+func (app *Operation) collectRoleMembers(direct, composite bool) ([]roleMember, error) {
+	if err := app.Authenticate(); err != nil {
+		return nil, err
+	}
+	if err := app.FindClientIdByName(); err != nil {
+		return nil, err
+	}
+
+	roleId := app.findRole(app.roleName, false)
+	if roleId == "" {
+		return nil, fmt.Errorf("роль %s клиента %s не найдена", app.roleName, app.ClientIdName)
+	}
+
+	collected := make(map[string]*roleMember)
+	add := func(members []Member, source string) {
+		for _, member := range members {
+			entry, ok := collected[member.ID]
+			if !ok {
+				entry = &roleMember{member: member}
+				collected[member.ID] = entry
+			}
+			entry.sources = append(entry.sources, source)
+		}
+	}
+
+	if subGroupId := app.findRoleSubgroup(); subGroupId != "" {
+		members, err := app.getGroupMembers(subGroupId)
+		if err != nil {
+			return nil, err
+		}
+		add(members, "/"+rolesGroupName+"/"+app.ClientIdName+"/"+app.roleName)
+	} else {
+		logWarn("Подгруппа %s/%s/%s не найдена", rolesGroupName, app.ClientIdName, app.roleName)
+	}
+
+	if direct {
+		users, err := app.getRoleUsers(app.roleName)
+		if err != nil {
+			return nil, err
+		}
+		add(users, "direct")
+	}
+
+	if composite {
+		logWarn("Составные роли ищутся среди ролей клиента %s и realm: цепочки через составные роли других клиентов не учитываются",
+			app.ClientIdName)
+		parents, err := app.findParentRoles(roleId)
+		if err != nil {
+			return nil, err
+		}
+		for _, parent := range parents {
+			app.addCompositeRoleMembers(parent, add)
+		}
+	}
+
+	result := make([]roleMember, 0, len(collected))
+	for _, entry := range collected {
+		result = append(result, *entry)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].member.Username < result[j].member.Username
+	})
+	return result, nil
+}
+
+// findRoleSubgroup находит подгруппу роли в дереве Roles, ничего не создавая
+func (app *Operation) findRoleSubgroup() string {
+	rolesGroup, err := app.findRolesGroup()
+	if err != nil {
+		return ""
+	}
+	app.parentGroupId = app.findClientSubgroup(rolesGroup)
+	if app.parentGroupId == "" {
+		return ""
+	}
+	return app.getSubGroupByName(app.roleName)
+}
+
+// findParentRoles находит составные роли клиента и realm, которые прямо или транзитивно включают роль.
+// Состав каждой составной роли запрашивается один раз. Цепочки через составные роли других клиентов не учитываются
+func (app *Operation) findParentRoles(roleId string) ([]RoleDetails, error) {
+	candidates, err := app.listCompositeRoles()
+	if err != nil {
+		return nil, err
+	}
+	children := app.compositeChildren(candidates)
+
+	var parents []RoleDetails
+	found := map[string]bool{roleId: true}
+	queue := []string{roleId}
+	for len(queue) > 0 {
+		childId := queue[0]
+		queue = queue[1:]
+		for _, candidate := range candidates {
+			if found[candidate.ID] || !children[candidate.ID][childId] {
+				continue
+			}
+			found[candidate.ID] = true
+			parents = append(parents, candidate)
+			queue = append(queue, candidate.ID)
+		}
+	}
+	return parents, nil
+}
+
+// listCompositeRoles возвращает все составные роли клиента и realm
+func (app *Operation) listCompositeRoles() ([]RoleDetails, error) {
+	var composites []RoleDetails
+	for _, endpoint := range []string{clientRolesEndpoint, realmRolesEndpoint} {
+		roles, err := fetchAllPages[RoleDetails](func(first, max int) (*resty.Response, error) {
+			return app.client.R().
+				SetPathParams(map[string]string{
+					"instance": app.realm,
+					"clientId": app.clientId,
+				}).
+				SetQueryParams(app.setBriefRepresentation(map[string]string{
+					"first": strconv.Itoa(first),
+					"max":   strconv.Itoa(max),
+				}, false)).
+				Get(endpoint)
+		})
+		if err != nil {
+			app.AddError(fmt.Sprintf("Ошибка получения списка ролей: %v", err))
+			return nil, err
+		}
+		for _, role := range roles {
+			if role.Composite {
+				composites = append(composites, role)
+			}
+		}
+	}
+	return composites, nil
+}
+
+// compositeChildren возвращает для каждой составной роли ID ролей, непосредственно входящих в её состав.
+// Роль, состав которой получить не удалось, считается пустой
+func (app *Operation) compositeChildren(composites []RoleDetails) map[string]map[string]bool {
+	children := make(map[string]map[string]bool, len(composites))
+	for _, parent := range composites {
+		var roles []RoleDetails
+		if err := app.getJSON(roleCompositesEndpoint, map[string]string{"roleId": parent.ID}, &roles); err != nil {
+			continue
+		}
+		ids := make(map[string]bool, len(roles))
+		for _, role := range roles {
+			ids[role.ID] = true
+		}
+		children[parent.ID] = ids
+	}
+	return children
+}
+
+// addCompositeRoleMembers добавляет пользователей и участников групп, которым назначена составная роль.
+// Подгруппы наследуют роли родительской группы, поэтому учитываются и их участники
+func (app *Operation) addCompositeRoleMembers(parent RoleDetails, add func([]Member, string)) {
+	usersEndpoint, groupsEndpoint := realmRoleUsersEndpoint, realmRoleGroupsEndpoint
+	if parent.ClientRole {
+		usersEndpoint, groupsEndpoint = roleUsersEndpoint, clientRoleGroupsEndpoint
+	}
+	params := map[string]string{
+		"instance": app.realm,
+		"clientId": parent.ContainerID,
+		"role":     parent.Name,
+	}
+	source := "composite:" + parent.Name
+
+	users, err := fetchAllPages[Member](func(first, max int) (*resty.Response, error) {
+		return app.client.R().SetPathParams(params).
+			SetQueryParams(map[string]string{"first": strconv.Itoa(first), "max": strconv.Itoa(max)}).
+			Get(usersEndpoint)
+	})
+	if err != nil {
+		app.AddError(fmt.Sprintf("Ошибка получения пользователей роли %s: %v", parent.Name, err))
+		return
+	}
+	add(users, source)
+
+	groups, err := fetchAllPages[Group](func(first, max int) (*resty.Response, error) {
+		return app.client.R().SetPathParams(params).
+			SetQueryParams(map[string]string{"first": strconv.Itoa(first), "max": strconv.Itoa(max)}).
+			Get(groupsEndpoint)
+	})
+	if err != nil {
+		app.AddError(fmt.Sprintf("Ошибка получения групп роли %s: %v", parent.Name, err))
+		return
+	}
+	visited := make(map[string]bool)
+	for _, group := range groups {
+		app.addGroupTreeMembers(group, source, visited, add)
+	}
+}
+
+// addGroupTreeMembers добавляет участников группы и всех её подгрупп
+func (app *Operation) addGroupTreeMembers(group Group, source string, visited map[string]bool, add func([]Member, string)) {
+	if visited[group.ID] {
+		return
+	}
+	visited[group.ID] = true
+
+	if members, err := app.getGroupMembers(group.ID); err == nil {
+		add(members, source+" via "+group.Path)
+	}
+
+	subgroups, err := app.getGroupSubgroups(group.ID, "")
+	if err != nil {
+		app.AddError(fmt.Sprintf("Ошибка получения подгрупп группы %s: %v", group.Path, err))
+		return
+	}
+	for _, subgroup := range subgroups {
+		app.addGroupTreeMembers(subgroup, source, visited, add)
+	}
+}
@@ -0,0 +1,234 @@
+// naming.go применяет шаблон имени роли и проверяет политику именования
+//   - Политика задаётся в config.yaml по типу инстанса ("Employee") или паре ("Employee/Prod"),
+//     для отдельных клиентов - в clients; настройки клиента дополняют настройки инстанса
+//   - Шаблон вида "{{client}}_{{env}}_{{name}}" строит имя создаваемой роли из имени в запросе.
+//     Имя, уже построенное по шаблону, не изменяется
+//   - Существующие роли ищутся по имени из запроса; если такой роли нет, а роль с именем по шаблону есть,
+//     используется она. Так старые роли с именами не по политике можно переименовать и удалить
+//   - Имена создаваемых ролей проверяются при чтении строки: строка с нарушением не выполняется
+//     и отмечается ошибкой в команде validate
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Подстановки шаблона имени роли
+const (
+	placeholderName     = "{{name}}"
+	placeholderClient   = "{{client}}"
+	placeholderEnv      = "{{env}}"
+	placeholderInstance = "{{instance}}"
+)
+
+// Требования к регистру имени роли
+const (
+	caseLower = "lower"
+	caseUpper = "upper"
+)
+
+// placeholderPattern находит подстановки в шаблоне имени роли
+var placeholderPattern = regexp.MustCompile(`\{\{[^{}]*\}\}`)
+
+// NamingPolicy содержит правила именования ролей
+type NamingPolicy struct {
+	Template  string                  `yaml:"template"`   // Шаблон итогового имени, например "{{client}}_{{env}}_{{name}}"
+	Pattern   string                  `yaml:"pattern"`    // Регулярное выражение, которому должно соответствовать имя
+	Prefixes  []string                `yaml:"prefixes"`   // Допустимые префиксы имени
+	MaxLength int                     `yaml:"max_length"` // Максимальная длина имени в символах
+	Case      string                  `yaml:"case"`       // Регистр имени: lower, upper
+	Forbidden string                  `yaml:"forbidden"`  // Запрещённые символы
+	Clients   map[string]NamingPolicy `yaml:"clients"`    // Настройки для отдельных клиентов по clientId
+}
+
+// naming возвращает политику именования для инстанса, окружения и клиента
+func (c *Config) naming(instance, environment, client string) NamingPolicy {
+	policy, ok := c.Naming[instance+"/"+environment]
+	if !ok {
+		policy = c.Naming[instance]
+	}
+	if override, ok := policy.Clients[client]; ok {
+		policy = policy.merge(override)
+	}
+	return policy
+}
+
+// merge дополняет политику заданными настройками клиента
+func (p NamingPolicy) merge(override NamingPolicy) NamingPolicy {
+	if override.Template != "" {
+		p.Template = override.Template
+	}
+	if override.Pattern != "" {
+		p.Pattern = override.Pattern
+	}
+	if len(override.Prefixes) > 0 {
+		p.Prefixes = override.Prefixes
+	}
+	if override.MaxLength > 0 {
+		p.MaxLength = override.MaxLength
+	}
+	if override.Case != "" {
+		p.Case = override.Case
+	}
+	if override.Forbidden != "" {
+		p.Forbidden = override.Forbidden
+	}
+	p.Clients = nil
+	return p
+}
+
+// validateNaming проверяет политики именования из настроек
+func validateNaming(naming map[string]NamingPolicy) error {
+	for key, policy := range naming {
+		if err := policy.validate(); err != nil {
+			return fmt.Errorf("naming %s: %w", key, err)
+		}
+		for client, override := range policy.Clients {
+			if err := override.validate(); err != nil {
+				return fmt.Errorf("naming %s, клиент %s: %w", key, client, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validate проверяет одну политику именования
+func (p NamingPolicy) validate() error {
+	if p.Template != "" {
+		if !strings.Contains(p.Template, placeholderName) {
+			return fmt.Errorf("в шаблоне %s нет подстановки %s", p.Template, placeholderName)
+		}
+		for _, placeholder := range placeholderPattern.FindAllString(p.Template, -1) {
+			switch placeholder {
+			case placeholderName, placeholderClient, placeholderEnv, placeholderInstance:
+			default:
+				return fmt.Errorf("неизвестная подстановка %s в шаблоне. Допустимые: %s, %s, %s, %s", placeholder,
+					placeholderName, placeholderClient, placeholderEnv, placeholderInstance)
+			}
+		}
+	}
+	if p.Pattern != "" {
+		if _, err := regexp.Compile(p.Pattern); err != nil {
+			return fmt.Errorf("некорректное регулярное выражение %s: %w", p.Pattern, err)
+		}
+	}
+	if p.Case != "" && p.Case != caseLower && p.Case != caseUpper {
+		return fmt.Errorf("неизвестный регистр %s. Допустимые: %s, %s", p.Case, caseLower, caseUpper)
+	}
+	if p.MaxLength < 0 {
+		return fmt.Errorf("max_length не может быть отрицательным")
+	}
+	return nil
+}
+
+// applyTemplate строит итоговое имя роли по шаблону. Имя, уже соответствующее шаблону, возвращается как есть
+func (p NamingPolicy) applyTemplate(name, instance, environment, client string) string {
+	if p.Template == "" || name == "" {
+		return name
+	}
+	replacer := strings.NewReplacer(placeholderClient, client, placeholderEnv, environment, placeholderInstance, instance)
+
+	parts := strings.Split(p.Template, placeholderName)
+	quoted := make([]string, 0, len(parts))
+	for _, part := range parts {
+		quoted = append(quoted, regexp.QuoteMeta(replacer.Replace(part)))
+	}
+	if regexp.MustCompile("^" + strings.Join(quoted, "(.+)") + "$").MatchString(name) {
+		return name
+	}
+	return strings.ReplaceAll(replacer.Replace(p.Template), placeholderName, name)
+}
+
+// check проверяет имя роли по политике и возвращает описание всех нарушений
+func (p NamingPolicy) check(name string) error {
+	var violations []string
+	if p.Pattern != "" && !regexp.MustCompile(p.Pattern).MatchString(name) {
+		violations = append(violations, "не соответствует шаблону "+p.Pattern)
+	}
+	if len(p.Prefixes) > 0 && !hasAnyPrefix(name, p.Prefixes) {
+		violations = append(violations, "должно начинаться с "+strings.Join(p.Prefixes, ", "))
+	}
+	if p.MaxLength > 0 && utf8.RuneCountInString(name) > p.MaxLength {
+		violations = append(violations, "длиннее "+strconv.Itoa(p.MaxLength)+" символов")
+	}
+	if p.Case == caseLower && name != strings.ToLower(name) {
+		violations = append(violations, "должно быть в нижнем регистре")
+	}
+	if p.Case == caseUpper && name != strings.ToUpper(name) {
+		violations = append(violations, "должно быть в верхнем регистре")
+	}
+	if p.Forbidden != "" && strings.ContainsAny(name, p.Forbidden) {
+		violations = append(violations, fmt.Sprintf("содержит запрещённые символы %q", p.Forbidden))
+	}
+	if len(violations) > 0 {
+		return fmt.Errorf("имя роли %s нарушает политику именования: %s", name, strings.Join(violations, "; "))
+	}
+	return nil
+}
+
+// hasAnyPrefix проверяет, начинается ли имя с одного из префиксов
+func hasAnyPrefix(name string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyNamingPolicy строит по шаблону и проверяет имена ролей, которые операция может создать.
+// Для имён существующих ролей имя по шаблону запоминается и используется, если роли с именем из запроса нет
+func (o *Operation) applyNamingPolicy() error {
+	policy := config.naming(o.instance, o.environment, o.ClientIdName)
+
+	for _, name := range []*string{&o.roleName, &o.targetRoleName} {
+		rendered := policy.applyTemplate(*name, o.instance, o.environment, o.ClientIdName)
+		if !o.createsRole(name) {
+			if rendered != *name {
+				if o.templatedNames == nil {
+					o.templatedNames = make(map[string]string)
+				}
+				o.templatedNames[*name] = rendered
+			}
+			continue
+		}
+
+		if rendered != *name {
+			logInfo("Строка %d: имя роли %s построено по шаблону: %s", o.rowNum, *name, rendered)
+			*name = rendered
+		}
+		if err := policy.check(*name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createsRole сообщает, может ли операция создать роль с именем из поля name
+func (o *Operation) createsRole(name *string) bool {
+	switch {
+	case name == &o.roleName:
+		return o.action == actionCreate
+	case name == &o.targetRoleName:
+		return o.action == actionRename ||
+			((o.action == actionCopyMembers || o.action == actionMoveMembers) && o.hasOption(optionCreateTarget))
+	}
+	return false
+}
+
+// resolveTemplatedNames заменяет имя существующей роли именем по шаблону, если роли с именем
+// из запроса в клиенте нет, а роль с именем по шаблону есть. Вызывается после поиска клиента
+func (o *Operation) resolveTemplatedNames() {
+	for _, name := range []*string{&o.roleName, &o.targetRoleName} {
+		rendered, ok := o.templatedNames[*name]
+		if !ok || o.findRole(*name, false) != "" || o.findRole(rendered, false) == "" {
+			continue
+		}
+		logInfo("Строка %d: роли %s нет, используется роль по шаблону %s", o.rowNum, *name, rendered)
+		*name = rendered
+	}
+}
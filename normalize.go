@@ -0,0 +1,177 @@
+// normalize.go приводит логины к виду, в котором они хранятся в Keycloak
+//   - Отбрасывает доменный префикс (DOMAIN\ivanov) и суффикс настроенных доменов (ivanov@corp.ru)
+//   - Приводит к нижнему регистру и убирает точки в конце
+//   - Заменяет кириллические буквы, похожие на латинские, с предупреждением
+//   - Подменяет старые логины новыми по файлу псевдонимов
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// NormalizeConfig содержит настройки нормализации логинов
+type NormalizeConfig struct {
+	Domains     []string `yaml:"domains"`      // Домены, суффикс @domain которых отбрасывается при поиске по логину
+	AliasesFile string   `yaml:"aliases_file"` // YAML-файл "старый логин: новый логин"
+}
+
+// homoglyphs сопоставляет кириллические буквы латинским, которые выглядят так же.
+// Строчные к, м, т, в, н и заглавные В, Н на латинские буквы не похожи и не заменяются
+var homoglyphs = map[rune]rune{
+	'а': 'a', 'е': 'e', 'о': 'o', 'р': 'p', 'с': 'c', 'у': 'y', 'х': 'x', 'і': 'i', 'ј': 'j', 'ѕ': 's',
+	'А': 'A', 'Е': 'E', 'К': 'K', 'М': 'M', 'О': 'O', 'Р': 'P', 'С': 'C', 'Т': 'T', 'У': 'Y', 'Х': 'X',
+	'І': 'I', 'Ј': 'J', 'Ѕ': 'S',
+}
+
+// loginAliases содержит загруженные псевдонимы логинов
+var loginAliases = make(map[string]string)
+
+// loadAliases загружает файл псевдонимов; относительный путь считается от директории настроек
+func loadAliases(configDir string) error {
+	path := config.Normalize.AliasesFile
+	if path == "" {
+		return nil
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(configDir, path)
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		logWarn("Файл псевдонимов логинов %s не найден", path)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка чтения псевдонимов %s: %w", path, err)
+	}
+
+	var aliases map[string]string
+	if err := yaml.Unmarshal(data, &aliases); err != nil {
+		return fmt.Errorf("ошибка разбора псевдонимов %s: %w", path, err)
+	}
+	for old, current := range aliases {
+		loginAliases[strings.ToLower(strings.TrimSpace(old))] = strings.TrimSpace(current)
+	}
+	logInfo("Загружено псевдонимов логинов: %d", len(loginAliases))
+	return nil
+}
+
+// normalizeIdentifier приводит значение идентификатора к каноническому виду и сообщает о каждой замене
+func (app *Operation) normalizeIdentifier(ldap, strategy, value string) string {
+	result := canonicalIdentifier(strategy, value)
+
+	if result.homoglyphs != "" {
+		logWarn("Логин %s содержит кириллические буквы, похожие на латинские, заменено на %s", ldap, result.homoglyphs)
+	} else if result.cyrillic {
+		logWarn("Логин %s содержит кириллические буквы, замена невозможна", ldap)
+	}
+	if result.aliasOf != "" {
+		app.addOutcome(ldap, "псевдоним", result.aliasOf+" -> "+result.value)
+	}
+	if result.value != value {
+		app.addOutcome(ldap, "нормализован", value+" -> "+result.value)
+	}
+	return result.value
+}
+
+// normalization содержит результат нормализации идентификатора и выполненные замены
+type normalization struct {
+	value      string // Каноническое значение
+	homoglyphs string // Значение после замены кириллических букв, если замена была
+	cyrillic   bool   // В значении остались кириллические буквы, которые заменить нельзя
+	aliasOf    string // Логин, подменённый по файлу псевдонимов
+}
+
+// canonicalIdentifier приводит значение к каноническому виду без сообщений. Используется перед поиском
+// в Keycloak и для сравнения логинов из разных строк
+func canonicalIdentifier(strategy, value string) normalization {
+	var result normalization
+	normalized := strings.TrimRight(strings.TrimSpace(value), ".")
+
+	if replaced, ok := replaceHomoglyphs(normalized); ok {
+		result.homoglyphs = replaced
+		normalized = replaced
+	} else if hasCyrillic(normalized) {
+		result.cyrillic = true
+	}
+
+	if strategy != identifierAttribute {
+		normalized = strings.ToLower(normalized)
+	}
+
+	if strategy == identifierUsername {
+		if _, login, found := strings.Cut(normalized, `\`); found {
+			normalized = login
+		}
+		normalized = stripDomainSuffix(normalized)
+
+		if alias, ok := loginAliases[normalized]; ok {
+			result.aliasOf = normalized
+			normalized = alias
+		}
+	}
+
+	result.value = normalized
+	return result
+}
+
+// stripDomainSuffix отбрасывает суффикс @domain для доменов из настроек
+func stripDomainSuffix(login string) string {
+	name, domain, found := strings.Cut(login, "@")
+	if !found {
+		return login
+	}
+	for _, configured := range config.Normalize.Domains {
+		if strings.EqualFold(domain, configured) {
+			return name
+		}
+	}
+	return login
+}
+
+// replaceHomoglyphs заменяет кириллические буквы на латинские, если строка смешивает алфавиты
+// и все кириллические буквы имеют латинских двойников
+func replaceHomoglyphs(value string) (string, bool) {
+	if !hasCyrillic(value) {
+		return value, false
+	}
+
+	hasLatin := false
+	for _, r := range value {
+		if r < unicode.MaxASCII && unicode.IsLetter(r) {
+			hasLatin = true
+		}
+		if unicode.Is(unicode.Cyrillic, r) {
+			if _, ok := homoglyphs[r]; !ok {
+				return value, false
+			}
+		}
+	}
+	if !hasLatin {
+		return value, false
+	}
+
+	return strings.Map(func(r rune) rune {
+		if latin, ok := homoglyphs[r]; ok {
+			return latin
+		}
+		return r
+	}, value), true
+}
+
+// hasCyrillic проверяет наличие кириллических букв
+func hasCyrillic(value string) bool {
+	for _, r := range value {
+		if unicode.Is(unicode.Cyrillic, r) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,26 @@
+package main
+
+import "testing"
+
+func TestReplaceHomoglyphs(t *testing.T) {
+	tests := []struct {
+		value    string
+		want     string
+		replaced bool
+	}{
+		{"ivаnov", "ivanov", true},        // кириллическая "а"
+		{"pеtrоv", "petrov", true},        // кириллические "е" и "о"
+		{"ivanov", "ivanov", false},       // только латиница
+		{"иванов", "иванов", false},       // только кириллица
+		{"vоrоnin", "voronin", true},      // кириллические "о"
+		{"nаvаlnyн", "nаvаlnyн", false},   // "н" на "h" не похожа
+		{"bаkulin.в", "bаkulin.в", false}, // "в" на "b" не похожа
+		{"ivаnov.т", "ivаnov.т", false},   // строчная "т" на "t" не похожа
+	}
+	for _, test := range tests {
+		got, replaced := replaceHomoglyphs(test.value)
+		if got != test.want || replaced != test.replaced {
+			t.Errorf("replaceHomoglyphs(%q) = %q, %v, ожидалось %q, %v", test.value, got, replaced, test.want, test.replaced)
+		}
+	}
+}
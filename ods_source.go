@@ -0,0 +1,201 @@
+// ods_source.go читает книги LibreOffice/OpenOffice Calc (.ods)
+//   - Книга - zip-архив, ячейки листов хранятся в content.xml
+//   - Значение ячейки берётся из отображаемого текста, как его видит пользователь
+//   - Повторяющиеся строки и ячейки (number-rows-repeated/number-columns-repeated) разворачиваются,
+//     пустые хвосты листа не материализуются
+package main
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Файлы внутри архива .ods
+const (
+	odsContentFile  = "content.xml"
+	odsMimetypeFile = "mimetype"
+	odsMimetype     = "application/vnd.oasis.opendocument.spreadsheet"
+)
+
+// odsSource читает листы запросов книги LibreOffice Calc
+type odsSource struct{}
+
+// Read читает листы запросов книги .ods
+func (odsSource) Read(path string) ([]RequestTable, error) {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка открытия файла: %w", err)
+	}
+	defer archive.Close()
+
+	if mimetype := readZipEntry(&archive.Reader, odsMimetypeFile); mimetype != "" && mimetype != odsMimetype {
+		return nil, fmt.Errorf("файл не является таблицей OpenDocument: %s", mimetype)
+	}
+
+	content, err := archive.Open(odsContentFile)
+	if err != nil {
+		return nil, fmt.Errorf("в книге нет %s: %w", odsContentFile, err)
+	}
+	defer content.Close()
+
+	sheets, err := parseODSContent(content)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения книги .ods: %w", err)
+	}
+	return requestSheetTables(newExcelConfig(path), sheets)
+}
+
+// readZipEntry возвращает содержимое небольшого файла архива или пустую строку
+func readZipEntry(archive *zip.Reader, name string) string {
+	file, err := archive.Open(name)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, 1024))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// odsSheetBuilder накапливает ячейки листа; пустые строки и ячейки откладываются,
+// пока после них не встретится значение
+type odsSheetBuilder struct {
+	sheet        workbookSheet
+	row, col     int
+	rowHasValues bool
+}
+
+// addCell добавляет ячейку, повторённую repeat раз
+func (b *odsSheetBuilder) addCell(value string, repeat int) {
+	if value != "" {
+		for i := 0; i < repeat; i++ {
+			b.sheet.Rows = setSheetCell(b.sheet.Rows, b.row, b.col+i, value)
+		}
+		b.rowHasValues = true
+	}
+	b.col += repeat
+}
+
+// endRow завершает строку; непустая повторённая строка копируется repeat раз
+func (b *odsSheetBuilder) endRow(repeat int) {
+	if b.rowHasValues {
+		for i := 1; i < repeat; i++ {
+			copied := append([]string(nil), b.sheet.Rows[b.row]...)
+			b.sheet.Rows = setSheetCell(b.sheet.Rows, b.row+i, 0, "")
+			b.sheet.Rows[b.row+i] = copied
+		}
+	}
+	b.row += repeat
+	b.col = 0
+	b.rowHasValues = false
+}
+
+// parseODSContent читает листы из content.xml
+func parseODSContent(r io.Reader) ([]workbookSheet, error) {
+	decoder := xml.NewDecoder(r)
+	var sheets []workbookSheet
+	var builder *odsSheetBuilder
+	var cell *strings.Builder
+	var paragraphs int
+	rowRepeat, cellRepeat, skipDepth := 1, 1, 0
+
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return sheets, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if skipDepth > 0 {
+				skipDepth++
+				continue
+			}
+			switch t.Name.Local {
+			case "table":
+				builder = &odsSheetBuilder{sheet: workbookSheet{Name: xmlAttr(t, "name")}}
+			case "table-row":
+				rowRepeat = xmlRepeat(t, "number-rows-repeated")
+			case "table-cell", "covered-table-cell":
+				cell = &strings.Builder{}
+				paragraphs = 0
+				cellRepeat = xmlRepeat(t, "number-columns-repeated")
+			case "annotation":
+				// Примечания к ячейке не являются её значением
+				skipDepth = 1
+			case "p":
+				if cell != nil && paragraphs > 0 {
+					cell.WriteString("\n")
+				}
+				paragraphs++
+			case "s":
+				if cell != nil {
+					cell.WriteString(strings.Repeat(" ", xmlRepeat(t, "c")))
+				}
+			case "tab":
+				if cell != nil {
+					cell.WriteString("\t")
+				}
+			case "line-break":
+				if cell != nil {
+					cell.WriteString("\n")
+				}
+			}
+		case xml.EndElement:
+			if skipDepth > 0 {
+				skipDepth--
+				continue
+			}
+			switch t.Name.Local {
+			case "table":
+				if builder != nil {
+					sheets = append(sheets, builder.sheet)
+				}
+				builder = nil
+			case "table-row":
+				if builder != nil {
+					builder.endRow(rowRepeat)
+				}
+			case "table-cell", "covered-table-cell":
+				if builder != nil && cell != nil {
+					builder.addCell(cell.String(), cellRepeat)
+				}
+				cell = nil
+			}
+		case xml.CharData:
+			if cell != nil && skipDepth == 0 && paragraphs > 0 {
+				cell.Write(t)
+			}
+		}
+	}
+}
+
+// xmlAttr возвращает значение атрибута по локальному имени
+func xmlAttr(element xml.StartElement, name string) string {
+	for _, attr := range element.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// xmlRepeat возвращает число повторений из атрибута; по умолчанию 1
+func xmlRepeat(element xml.StartElement, name string) int {
+	count, err := strconv.Atoi(xmlAttr(element, name))
+	if err != nil || count < 1 {
+		return 1
+	}
+	return count
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// odsContent оборачивает таблицы в документ content.xml
+func odsContent(tables string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0"
+ xmlns:table="urn:oasis:names:tc:opendocument:xmlns:table:1.0"
+ xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0">
+<office:body><office:spreadsheet>` + tables + `</office:spreadsheet></office:body></office:document-content>`
+}
+
+func TestParseODSContent(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []workbookSheet
+		wantErr bool
+	}{
+		{
+			name: "повторяющиеся ячейки и строки",
+			content: odsContent(`<table:table table:name="Request">
+<table:table-row table:number-rows-repeated="2">
+ <table:table-cell table:number-columns-repeated="2"><text:p>Dev</text:p></table:table-cell>
+ <table:table-cell/><table:table-cell><text:p>x</text:p></table:table-cell>
+</table:table-row></table:table>`),
+			want: []workbookSheet{{Name: "Request", Rows: [][]string{{"Dev", "Dev", "", "x"}, {"Dev", "Dev", "", "x"}}}},
+		},
+		{
+			name: "пустые хвосты листа не разворачиваются",
+			content: odsContent(`<table:table table:name="Request">
+<table:table-row><table:table-cell><text:p>a</text:p></table:table-cell>
+ <table:table-cell table:number-columns-repeated="16384"/></table:table-row>
+<table:table-row table:number-rows-repeated="1048575"><table:table-cell table:number-columns-repeated="16384"/></table:table-row>
+</table:table>`),
+			want: []workbookSheet{{Name: "Request", Rows: [][]string{{"a"}}}},
+		},
+		{
+			name: "абзацы, пробелы и примечания",
+			content: odsContent(`<table:table table:name="Request"><table:table-row>
+ <table:table-cell><office:annotation><text:p>примечание</text:p></office:annotation><text:p>ivanov,<text:s text:c="2"/>petrov</text:p><text:p>sidorov</text:p></table:table-cell>
+</table:table-row></table:table>`),
+			want: []workbookSheet{{Name: "Request", Rows: [][]string{{"ivanov,  petrov\nsidorov"}}}},
+		},
+		{
+			name: "пустая строка между значениями сохраняется",
+			content: odsContent(`<table:table table:name="Request">
+<table:table-row><table:table-cell><text:p>1</text:p></table:table-cell></table:table-row>
+<table:table-row table:number-rows-repeated="2"><table:table-cell/></table:table-row>
+<table:table-row><table:table-cell/><table:table-cell><text:p>4</text:p></table:table-cell></table:table-row>
+</table:table><table:table table:name="Users"/>`),
+			want: []workbookSheet{
+				{Name: "Request", Rows: [][]string{{"1"}, nil, nil, {"", "4"}}},
+				{Name: "Users"},
+			},
+		},
+		{
+			name:    "повреждённый XML",
+			content: `<office:document-content><table:table`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseODSContent(strings.NewReader(tt.content))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseODSContent() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseODSContent() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestODSSourceRead(t *testing.T) {
+	tables, err := odsSource{}.Read("testdata/request.ods")
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if len(tables) != 1 {
+		t.Fatalf("Read() вернул таблиц: %d, ожидалась 1", len(tables))
+	}
+
+	table := tables[0]
+	wantHeader := []string{"Keycloak type", "Keycloak environment", "Action", "Client ID", "Role name", "User logins"}
+	if !reflect.DeepEqual(table.Header, wantHeader) {
+		t.Errorf("Header = %q, want %q", table.Header, wantHeader)
+	}
+	row := []string{"Employee", "Employee", "Associate users with role", "app", "viewer", "ivanov,  petrov\nsidorov"}
+	if wantRows := [][]string{row, row}; !reflect.DeepEqual(table.Rows, wantRows) {
+		t.Errorf("Rows = %q, want %q", table.Rows, wantRows)
+	}
+	if len(table.Sheets) != 2 || table.Sheets[1].Name != "Users" {
+		t.Errorf("Sheets = %v, ожидались листы Request и Users", table.Sheets)
+	}
+}
@@ -0,0 +1,144 @@
+// offboard.go реализует команду offboard
+//   - Ищет членство пользователей в подгруппах Roles во всех инстансах и окружениях
+//   - Удаляет пользователей из найденных подгрупп
+//   - Формирует отчёт об отзыве доступа
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+)
+
+const userGroupsEndpoint = "/admin/realms/{instance}/users/{userId}/groups"
+
+// runOffboard выполняет команду offboard
+func (a *App) runOffboard(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("offboard", flag.ContinueOnError)
+	var target targetFlags
+	var report reportFlags
+	target.register(fs)
+	report.register(fs, formatCSV)
+	dryRun := fs.Bool("dry-run", false, "только показать членство, ничего не удалять")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Использование: offboard [параметры] login1 [login2 ...]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := validateReportFormat(report.format); err != nil {
+		return err
+	}
+
+	logins := parseLDAPs(strings.Join(fs.Args(), ","))
+	if len(logins) == 0 {
+		fs.Usage()
+		return fmt.Errorf("не указаны логины")
+	}
+
+	operations, err := target.targets()
+	if err != nil {
+		return err
+	}
+
+	result := Report{Headers: []string{"Login", "Instance", "Environment", "Group", "Status"}}
+	for i := range operations {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		operations[i].offboardUsers(logins, *dryRun, &result)
+		operations[i].printErrors()
+	}
+
+	if report.output == "" && report.format != formatTable {
+		if report.output, err = defaultReportPath("offboard", report.format); err != nil {
+			return err
+		}
+	}
+	if err := result.writeTable(a.consoleLogger.Writer()); err != nil {
+		return err
+	}
+	if report.output == "" {
+		return nil
+	}
+	if err := result.Write(report.format, report.output); err != nil {
+		return err
+	}
+	logInfo("Отчёт об отзыве доступа сохранён в %s", report.output)
+	return nil
+}
+
+// offboardUsers удаляет пользователей из всех подгрупп дерева Roles в одном инстансе.
+// Для каждого логина в отчёте есть хотя бы одна строка, в том числе когда ролей нет
+func (app *Operation) offboardUsers(logins []string, dryRun bool, report *Report) {
+	logInfo("Offboarding: %s %s", app.instance, app.environment)
+	if err := app.Authenticate(); err != nil {
+		for _, login := range logins {
+			report.AddRow(login, app.instance, app.environment, "", "ошибка аутентификации")
+		}
+		return
+	}
+
+	for _, login := range logins {
+		userId := app.getAnyUserIdByLdap(login)
+		if userId == "" {
+			report.AddRow(login, app.instance, app.environment, "", "пользователь не найден")
+			continue
+		}
+
+		groups, err := app.getUserRoleGroups(userId)
+		if err != nil {
+			report.AddRow(login, app.instance, app.environment, "", "ошибка получения групп")
+			continue
+		}
+		if len(groups) == 0 {
+			report.AddRow(login, app.instance, app.environment, "", "нет ролей")
+			continue
+		}
+
+		for _, group := range groups {
+			status := "будет удалён"
+			if !dryRun {
+				status = "удалён"
+				if !app.removeMember(userId, group.ID) {
+					status = "ошибка удаления"
+				}
+			}
+			report.AddRow(login, app.instance, app.environment, group.Path, status)
+		}
+	}
+}
+
+// getUserRoleGroups возвращает группы пользователя, входящие в дерево Roles
+func (app *Operation) getUserRoleGroups(userId string) ([]Group, error) {
+	groups, err := fetchAllPages[Group](func(first, max int) (*resty.Response, error) {
+		return app.client.R().
+			SetPathParams(map[string]string{
+				"instance": app.realm,
+				"userId":   userId,
+			}).
+			SetQueryParams(map[string]string{
+				"first": strconv.Itoa(first),
+				"max":   strconv.Itoa(max),
+			}).
+			Get(userGroupsEndpoint)
+	})
+	if err != nil {
+		app.AddError(fmt.Sprintf("Ошибка получения групп пользователя %s: %v", userId, err))
+		return nil, err
+	}
+
+	prefix := "/" + rolesGroupName + "/"
+	var roleGroups []Group
+	for _, group := range groups {
+		if strings.HasPrefix(group.Path, prefix) {
+			roleGroups = append(roleGroups, group)
+		}
+	}
+	return roleGroups, nil
+}
@@ -12,17 +12,37 @@ import (
 
 // Operation представляет одну операцию для обработки в Keycloak
 type Operation struct {
-	client        *resty.Client
-	ClientIdName  string
-	realm         string
-	action        string
-	roleName      string
-	ldaps         []string
-	ldapsString   string
-	clientId      string
-	parentGroupId string
-	errors        map[int]string
-	errorCounter  int
+	client         *resty.Client
+	ClientIdName   string
+	realm          string
+	instance       string
+	environment    string
+	action         string
+	roleName       string
+	targetRoleName string
+	bundle         string            // Набор ролей, из которого развёрнута операция
+	templatedNames map[string]string // Имена существующих ролей по шаблону: имя из запроса -> имя по шаблону
+	ldaps          []string
+	ldapsString    string
+	options        []string
+	clientId       string
+	parentGroupId  string
+	errors         map[int]string
+	errorCounter   int
+	outcomes       []Outcome
+	server         *ServerInfo
+	readOnly       bool
+	failed         bool // Операция завершилась ошибкой, а не только замечаниями
+	sourceFile     string
+	rowNum         int
+	extras         []ExtraValue
+}
+
+// Outcome содержит результат обработки одного пользователя в рамках операции
+type Outcome struct {
+	Login  string // Логин пользователя
+	Status string // Итог обработки
+	Detail string // Подробности
 }
 
 // Глобальные переменные
@@ -31,6 +51,18 @@ var (
 	userSearchLimit = rate.NewLimiter(rate.Every(time.Second), 10) // rate limiter
 )
 
+// newOperation создает операцию для пары инстанс/окружение
+func newOperation(instance, environment string) Operation {
+	baseURL, realm := getURLAndRealm(instance, environment)
+	return Operation{
+		client:      createHTTPClient(baseURL),
+		realm:       realm,
+		instance:    instance,
+		environment: environment,
+		errors:      make(map[int]string),
+	}
+}
+
 // AddError добавляет ошибку в коллекцию ошибок операции
 func (o *Operation) AddError(error string) {
 	o.errorCounter++
@@ -40,6 +72,42 @@ func (o *Operation) AddError(error string) {
 	logFile.WriteString(fmt.Sprintf("%s: %s\n", time.Now().Format("2006-01-02 15:04:05"), error))
 }
 
+// fail добавляет ошибку и отмечает операцию как завершившуюся ошибкой.
+// AddError без fail используется и для замечаний, которые не мешают выполнению
+func (o *Operation) fail(error string) {
+	o.AddError(error)
+	o.failed = true
+}
+
+// addOutcome сохраняет результат обработки пользователя и пишет его в лог
+func (o *Operation) addOutcome(login, status, detail string) {
+	o.recordOutcome(login, status, detail)
+	logInfo("%s: %s %s", login, status, detail)
+}
+
+// recordOutcome сохраняет результат обработки пользователя без записи в лог
+func (o *Operation) recordOutcome(login, status, detail string) {
+	o.outcomes = append(o.outcomes, Outcome{Login: login, Status: status, Detail: detail})
+}
+
+// displayRoleName возвращает роль для отчётов, для пары ролей - "исходная -> целевая"
+func (o *Operation) displayRoleName() string {
+	if o.targetRoleName == "" {
+		return o.roleName
+	}
+	return o.roleName + " " + rolePairSeparator + " " + o.targetRoleName
+}
+
+// hasOption проверяет, указана ли опция в колонке Options
+func (o *Operation) hasOption(option string) bool {
+	for _, opt := range o.options {
+		if opt == option {
+			return true
+		}
+	}
+	return false
+}
+
 // PrintErrors выводит ошибки в консоль
 func (o *Operation) printErrors() {
 	if len(o.errors) == 0 {
@@ -0,0 +1,157 @@
+// report.go предоставляет функционал для вывода отчётов команд
+//   - Таблица в консоль
+//   - Файлы CSV, JSON и Excel
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Форматы отчётов
+const (
+	formatTable = "table"
+	formatCSV   = "csv"
+	formatJSON  = "json"
+	formatExcel = "xlsx"
+	reportsDir  = "reports"
+)
+
+// Report представляет табличный отчёт команды
+type Report struct {
+	Headers []string
+	Rows    [][]string
+}
+
+// AddRow добавляет строку в отчёт
+func (r *Report) AddRow(values ...string) {
+	r.Rows = append(r.Rows, values)
+}
+
+// validateReportFormat проверяет, что формат отчёта поддерживается
+func validateReportFormat(format string) error {
+	switch format {
+	case formatTable, formatCSV, formatJSON, formatExcel:
+		return nil
+	default:
+		return fmt.Errorf("неизвестный формат отчёта: %s. Допустимые: %s, %s, %s, %s",
+			format, formatTable, formatCSV, formatJSON, formatExcel)
+	}
+}
+
+// defaultReportPath возвращает путь к файлу отчёта в директории reports рядом с исполняемым файлом
+func defaultReportPath(name, format string) (string, error) {
+	exeDir, err := executableDir()
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(exeDir, reportsDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("ошибка создания директории %s: %w", dir, err)
+	}
+	return filepath.Join(dir, fmt.Sprintf("%s_%s.%s", name, time.Now().Format("20060102_150405"), format)), nil
+}
+
+// Write выводит отчёт в указанном формате в файл или, если путь пуст, в консоль
+func (r *Report) Write(format, path string) error {
+	if format == formatExcel {
+		if path == "" {
+			return fmt.Errorf("для формата %s нужно указать файл отчёта", formatExcel)
+		}
+		return r.writeExcel(path)
+	}
+
+	var w io.Writer = os.Stdout
+	if path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("ошибка создания файла отчёта: %w", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	switch format {
+	case formatCSV:
+		return r.writeCSV(w)
+	case formatJSON:
+		return r.writeJSON(w)
+	default:
+		return r.writeTable(w)
+	}
+}
+
+// writeTable выводит отчёт выровненной таблицей
+func (r *Report) writeTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(r.Headers, "\t"))
+	for _, row := range r.Rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// writeCSV выводит отчёт в формате CSV
+func (r *Report) writeCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(r.Headers); err != nil {
+		return err
+	}
+	if err := cw.WriteAll(r.Rows); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+// writeJSON выводит отчёт массивом объектов, ключи которых - заголовки колонок
+func (r *Report) writeJSON(w io.Writer) error {
+	records := make([]map[string]string, 0, len(r.Rows))
+	for _, row := range r.Rows {
+		record := make(map[string]string, len(r.Headers))
+		for i, header := range r.Headers {
+			if i < len(row) {
+				record[header] = row[i]
+			}
+		}
+		records = append(records, record)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(records)
+}
+
+// writeExcel сохраняет отчёт в Excel-файл
+func (r *Report) writeExcel(path string) error {
+	f := excelize.NewFile()
+	defer closeExcelFile(f)
+
+	sheet := f.GetSheetName(0)
+	if err := f.SetSheetRow(sheet, "A1", &r.Headers); err != nil {
+		return err
+	}
+	for i, row := range r.Rows {
+		cell, err := excelize.CoordinatesToCellName(1, i+2)
+		if err != nil {
+			return err
+		}
+		if err := f.SetSheetRow(sheet, cell, &row); err != nil {
+			return err
+		}
+	}
+
+	if err := f.SaveAs(path); err != nil {
+		return fmt.Errorf("ошибка сохранения отчёта %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+// results.go формирует отчёт о результатах обработки файлов запросов
+//   - Строка на каждый итог по пользователю и итоговая строка на операцию
+//   - Дополнительные колонки файлов запросов переносятся в отчёт как есть
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// resultsReportName задаёт префикс имени файла отчёта о результатах
+const resultsReportName = "result"
+
+// resultRow содержит строку отчёта о результатах до добавления дополнительных колонок
+type resultRow struct {
+	values []string
+	extras []ExtraValue
+}
+
+// ResultsReport накапливает результаты операций за запуск
+type ResultsReport struct {
+	rows         []resultRow
+	extraHeaders []string
+}
+
+// resultHeaders содержит основные колонки отчёта о результатах
+var resultHeaders = []string{"File", "Row", "Instance", "Environment", "Action", "Client", "Role", "Bundle", "Login", "Status", "Detail"}
+
+// Add добавляет в отчёт итоги операции
+func (r *ResultsReport) Add(operation *Operation) {
+	for _, extra := range operation.extras {
+		r.addExtraHeader(extra.Header)
+	}
+
+	base := []string{operation.sourceFile, strconv.Itoa(operation.rowNum), operation.instance,
+		operation.environment, operation.action, operation.ClientIdName, operation.displayRoleName(), operation.bundle}
+
+	for _, outcome := range operation.outcomes {
+		values := append(append([]string{}, base...), outcome.Login, outcome.Status, outcome.Detail)
+		r.rows = append(r.rows, resultRow{values: values, extras: operation.extras})
+	}
+
+	status := "OK"
+	detail := ""
+	if len(operation.errors) > 0 {
+		status = fmt.Sprintf("ошибок: %d", len(operation.errors))
+		messages := make([]string, 0, len(operation.errors))
+		for i := 1; i <= operation.errorCounter; i++ {
+			if msg, ok := operation.errors[i]; ok {
+				messages = append(messages, msg)
+			}
+		}
+		detail = strings.Join(messages, "; ")
+	}
+	values := append(append([]string{}, base...), "", status, detail)
+	r.rows = append(r.rows, resultRow{values: values, extras: operation.extras})
+}
+
+// addExtraHeader добавляет заголовок дополнительной колонки, если его ещё нет
+func (r *ResultsReport) addExtraHeader(header string) {
+	for _, existing := range r.extraHeaders {
+		if existing == header {
+			return
+		}
+	}
+	r.extraHeaders = append(r.extraHeaders, header)
+}
+
+// Report собирает табличный отчёт с дополнительными колонками
+func (r *ResultsReport) Report() Report {
+	report := Report{Headers: append(append([]string{}, resultHeaders...), r.extraHeaders...)}
+	for _, row := range r.rows {
+		values := append([]string{}, row.values...)
+		for _, header := range r.extraHeaders {
+			value := ""
+			for _, extra := range row.extras {
+				if extra.Header == header {
+					value = extra.Value
+					break
+				}
+			}
+			values = append(values, value)
+		}
+		report.Rows = append(report.Rows, values)
+	}
+	return report
+}
+
+// Save сохраняет отчёт о результатах в CSV-файл в директории reports
+func (r *ResultsReport) Save() {
+	if len(r.rows) == 0 {
+		return
+	}
+
+	path, err := defaultReportPath(resultsReportName, formatCSV)
+	if err == nil {
+		report := r.Report()
+		err = report.Write(formatCSV, path)
+	}
+	if err != nil {
+		logError("Не удалось сохранить отчёт о результатах: %v", err)
+		return
+	}
+	logInfo("Отчёт о результатах сохранён в %s", path)
+}
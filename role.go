@@ -72,47 +72,14 @@ func (app *Operation) createSubGroup(group string) string {
 	return result.ID
 }
 
-// getSubGroupByName ищет подгруппу по имени
+// getSubGroupByName ищет подгруппу роли по точному имени в подгруппе клиента
 func (app *Operation) getSubGroupByName(groupName string) string {
-	resp, err := app.client.R().SetPathParams(map[string]string{
-		"instance": app.realm,
-		"group":    app.parentGroupId,
-	}).Get("/admin/realms/{instance}/groups/{group}")
-
-	if err != nil || resp.StatusCode() != http.StatusOK {
-		app.AddError(fmt.Sprintf("Не удалось найти группу %s причина %d",
-			groupName, resp.StatusCode()))
-		return ""
-	}
-
-	var result Group
-	json.Unmarshal(resp.Body(), &result)
-
-	res, err := app.client.R().
-		SetPathParam("realm", app.realm).
-		SetPathParam("groupId", result.ID).
-		SetQueryParam("first", "0").
-		SetQueryParam("max", "250").
-		Get("/admin/realms/{realm}/groups/{groupId}/children")
-
+	id, err := app.findChildGroup(app.parentGroupId, groupName)
 	if err != nil {
-		app.AddError(fmt.Sprintf("Ошибка запроса подгрупп: %v", err))
-		return ""
-	}
-	if res.StatusCode() != http.StatusOK {
-		app.AddError(fmt.Sprintf("HTTP %d: не удалось получить подгруппы", res.StatusCode()))
+		app.AddError(fmt.Sprintf("Ошибка запроса подгрупп группы %s: %v", app.parentGroupId, err))
 		return ""
 	}
-
-	var subGroups []Group
-	json.Unmarshal(res.Body(), &subGroups)
-
-	for _, group := range subGroups {
-		if group.Name == groupName {
-			return group.ID
-		}
-	}
-	return ""
+	return id
 }
 
 // assignRole назначает роль группе
@@ -133,7 +100,7 @@ func (app *Operation) assignRole(role, id, groupId string) {
 }
 
 // addMember добавляет пользователя в группу
-func (app *Operation) addMember(userId, groupId string) {
+func (app *Operation) addMember(userId, groupId string) bool {
 	resp, err := app.client.R().SetPathParams(map[string]string{
 		"instance": app.realm,
 		"userId":   userId,
@@ -143,41 +110,69 @@ func (app *Operation) addMember(userId, groupId string) {
 	if err != nil || resp.StatusCode() != http.StatusNoContent {
 		app.AddError(fmt.Sprintf("Ошибка при добавлении участника %s в группу %s вызвана %d",
 			userId, groupId, resp.StatusCode()))
+		return false
 	}
+	return true
 }
 
 // processRole обрабатывает роль в зависимости от действия
 func (app *Operation) processRole(bar *progressbar.ProgressBar) {
 	roleId := app.findRole(app.roleName, false)
-	subGroupId := app.getSubGroupByName(app.roleName)
+	subGroupId := ""
+	if app.parentGroupId != "" {
+		subGroupId = app.getSubGroupByName(app.roleName)
+	}
 
-	if app.action == "Create new role and add users to this role" {
+	if app.action == actionCreate {
 		if roleId != "" || subGroupId != "" {
 			log.Printf("Роль %s уже существует, смена действия на 'Associate users with role'", app.roleName)
-			app.action = "Associate users with role"
+			app.action = actionAssociate
 		} else {
 			app.createRole(app.roleName)
 			roleId = app.findRole(app.roleName, true)
 			subGroupId = app.createSubGroup(app.roleName)
 			if roleId == "" || subGroupId == "" {
+				app.failed = true
 				return
 			}
 		}
 	}
 
 	switch app.action {
-	case "Associate users with role":
+	case actionAssociate:
 		if roleId == "" || subGroupId == "" {
-			app.AddError(fmt.Sprintf("Роль %s не существует. Пропуск LDAP:%s", app.roleName, app.ldapsString))
+			app.fail(fmt.Sprintf("Роль %s не существует. Пропуск LDAP:%s", app.roleName, app.ldapsString))
 			return
 		}
 		app.assignRoleWithGroup(roleId, subGroupId, bar)
-	case "Remove users from role":
+	case actionRemove:
 		if roleId == "" || subGroupId == "" {
-			app.AddError(fmt.Sprintf("Роль %s не существует. Пропуск LDAP:%s", app.roleName, app.ldapsString))
+			app.fail(fmt.Sprintf("Роль %s не существует. Пропуск LDAP:%s", app.roleName, app.ldapsString))
 			return
 		}
 		app.removeUsersFromGroup(subGroupId, bar)
+	case actionDelete:
+		if roleId == "" && subGroupId == "" {
+			app.fail(fmt.Sprintf("Роль %s не существует, удалять нечего", app.roleName))
+			return
+		}
+		if err := app.deleteRole(roleId, subGroupId); err != nil {
+			app.fail("ERROR: " + err.Error())
+		}
+	case actionRename:
+		if roleId == "" {
+			app.fail(fmt.Sprintf("Роль %s не существует, переименовать нельзя", app.roleName))
+			return
+		}
+		if err := app.renameRole(roleId, subGroupId); err != nil {
+			app.fail("ERROR: " + err.Error())
+		}
+	case actionCopyMembers, actionMoveMembers:
+		if subGroupId == "" {
+			app.fail(fmt.Sprintf("Подгруппа роли %s не существует, участников для переноса нет", app.roleName))
+			return
+		}
+		app.transferMembers(subGroupId, bar)
 	}
 }
 
@@ -190,7 +185,11 @@ func (app *Operation) assignRoleWithGroup(roleId string, subGroupId string, bar
 		if userId == "" {
 			continue
 		}
-		app.addMember(userId, subGroupId)
+		if app.addMember(userId, subGroupId) {
+			app.recordOutcome(ldap, outcomeAdded, app.roleName)
+		} else {
+			app.recordOutcome(ldap, outcomeFailed, "не удалось добавить в "+app.roleName)
+		}
 		_ = bar.Add(1)
 	}
 }
@@ -198,17 +197,21 @@ func (app *Operation) assignRoleWithGroup(roleId string, subGroupId string, bar
 // removeUsersFromGroup удаляет пользователей из группы
 func (app *Operation) removeUsersFromGroup(subGroupId string, bar *progressbar.ProgressBar) {
 	for _, ldap := range app.ldaps {
-		userId := app.getUserIdByLdap(ldap)
+		userId := app.getAnyUserIdByLdap(ldap)
 		if userId == "" {
 			continue
 		}
-		app.removeMember(userId, subGroupId)
+		if app.removeMember(userId, subGroupId) {
+			app.recordOutcome(ldap, outcomeRemoved, app.roleName)
+		} else {
+			app.recordOutcome(ldap, outcomeFailed, "не удалось удалить из "+app.roleName)
+		}
 		_ = bar.Add(1)
 	}
 }
 
 // removeMember удаляет пользователя из группы
-func (app *Operation) removeMember(userId, groupId string) {
+func (app *Operation) removeMember(userId, groupId string) bool {
 	resp, err := app.client.R().SetPathParams(map[string]string{
 		"instance": app.realm,
 		"userId":   userId,
@@ -218,19 +221,24 @@ func (app *Operation) removeMember(userId, groupId string) {
 	if err != nil || resp.StatusCode() != http.StatusNoContent {
 		app.AddError(fmt.Sprintf("Ошибка удаления участника %s из группы %s вызвана %d",
 			userId, groupId, resp.StatusCode()))
+		return false
 	}
+	return true
 }
 
-// findRole ищет роль по имени с возможностью повторных попыток
+// findRole ищет роль по имени. Проверка существования (create = false) выполняется одним запросом без повторов.
+// После создания роли (create = true) запрос повторяется, пока роль не появится. Условие повтора задаётся
+// для запроса, а не для общего клиента, чтобы условия не накапливались от вызова к вызову
 func (app *Operation) findRole(roleName string, create bool) string {
-	get, err := app.client.AddRetryCondition(
-		func(r *resty.Response, err error) bool {
-			return (r.StatusCode() == http.StatusNotFound) && create
-		},
-	).SetRetryCount(5).
-		SetRetryMaxWaitTime(20 * time.Second).
-		SetRetryWaitTime(4 * time.Second).
-		R().SetPathParams(map[string]string{
+	if create {
+		app.client.SetRetryCount(5).
+			SetRetryMaxWaitTime(20 * time.Second).
+			SetRetryWaitTime(4 * time.Second)
+	}
+	req := app.client.R().AddRetryCondition(func(r *resty.Response, err error) bool {
+		return create && r != nil && r.StatusCode() == http.StatusNotFound
+	})
+	get, err := req.SetPathParams(map[string]string{
 		"instance": app.realm,
 		"clientId": app.clientId,
 		"role":     roleName,
@@ -0,0 +1,266 @@
+// role_manage.go предоставляет функционал для изменения жизненного цикла ролей
+//   - Удаление клиентской роли вместе с подгруппой Roles/<client>/<role>
+//   - Сохранение удалённой роли для последующего восстановления
+//   - Переименование роли и подгруппы с сохранением участников
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	clientRoleEndpoint = "/admin/realms/{instance}/clients/{clientId}/roles/{role}"
+	groupEndpoint      = "/admin/realms/{instance}/groups/{groupId}"
+	deletedRolesDir    = "deleted_roles"
+)
+
+// DeletedRoleRecord описывает удалённую роль в объёме, достаточном для её восстановления
+type DeletedRoleRecord struct {
+	DeletedAt         string          `json:"deletedAt"`                   // Время удаления
+	Instance          string          `json:"instance"`                    // Тип Keycloak (Employee/Partner/Customer)
+	Environment       string          `json:"environment"`                 // Окружение (Prod/Dev/Test)
+	Realm             string          `json:"realm"`                       // Realm, в котором была роль
+	Client            string          `json:"client"`                      // Имя клиента
+	Role              json.RawMessage `json:"role,omitempty"`              // Представление роли из Keycloak
+	Composites        json.RawMessage `json:"composites,omitempty"`        // Составные роли, если роль композитная
+	GroupRoleMappings json.RawMessage `json:"groupRoleMappings,omitempty"` // Роли, назначенные подгруппе
+	Members           []string        `json:"members"`                     // Логины участников подгруппы
+	DirectUsers       []string        `json:"directUsers"`                 // Логины пользователей, которым роль назначена напрямую
+	RecreateRow       []string        `json:"recreateRow"`                 // Строка Excel для повторного создания роли с участниками подгруппы
+}
+
+// deleteRole удаляет подгруппу роли и клиентскую роль, предварительно сохранив их описание.
+// roleId пуст, если клиентской роли нет и удаляется только подгруппа
+func (app *Operation) deleteRole(roleId, subGroupId string) error {
+	record := DeletedRoleRecord{
+		DeletedAt:   time.Now().Format(time.RFC3339),
+		Instance:    app.instance,
+		Environment: app.environment,
+		Realm:       app.realm,
+		Client:      app.ClientIdName,
+		Members:     []string{},
+		DirectUsers: []string{},
+	}
+
+	if subGroupId != "" {
+		members, err := app.getGroupMembers(subGroupId)
+		if err != nil {
+			return fmt.Errorf("не удалось получить участников подгруппы роли %s, удаление отменено", app.roleName)
+		}
+		record.Members = memberUsernames(members)
+		record.GroupRoleMappings = app.fetchRaw(groupRoleMappingsEndpoint, map[string]string{"groupId": subGroupId})
+	}
+	if roleId != "" {
+		users, err := app.getRoleUsers(app.roleName)
+		if err != nil {
+			return fmt.Errorf("не удалось получить пользователей роли %s, удаление отменено", app.roleName)
+		}
+		record.DirectUsers = memberUsernames(users)
+	}
+
+	if err := app.confirmRoleDeletion(record.Members, record.DirectUsers); err != nil {
+		return err
+	}
+
+	if roleId != "" {
+		params := map[string]string{"clientId": app.clientId, "role": app.roleName}
+		record.Role = app.fetchRaw(clientRoleEndpoint, params)
+		if record.Role == nil {
+			return fmt.Errorf("не удалось получить описание роли %s, удаление отменено", app.roleName)
+		}
+		record.Composites = app.fetchRaw(clientRoleEndpoint+"/composites", params)
+	}
+	record.RecreateRow = []string{app.instance, app.environment, actionCreate,
+		app.ClientIdName, app.roleName, strings.Join(record.Members, ", ")}
+
+	name := fmt.Sprintf("%s_%s_%s_%s_%s.json", time.Now().Format("20060102_150405"),
+		app.instance, app.environment, app.ClientIdName, app.roleName)
+	path, err := writeJSONFile(deletedRolesDir, sanitizeFileName(name), record)
+	if err != nil {
+		return fmt.Errorf("не удалось сохранить описание роли %s перед удалением: %v. Удаление отменено", app.roleName, err)
+	}
+	logInfo("Описание роли %s сохранено в %s", app.roleName, path)
+
+	if subGroupId != "" && !app.deleteResource(groupEndpoint, map[string]string{"groupId": subGroupId}) {
+		return fmt.Errorf("подгруппа роли %s не удалена", app.roleName)
+	}
+	if roleId != "" && !app.deleteResource(clientRoleEndpoint, map[string]string{"clientId": app.clientId, "role": app.roleName}) {
+		return fmt.Errorf("подгруппа роли %s удалена, а клиентская роль нет: удалите её вручную, описание в %s",
+			app.roleName, path)
+	}
+
+	logInfo("Роль %s клиента %s удалена, участников подгруппы было: %d, с прямым назначением: %d",
+		app.roleName, app.ClientIdName, len(record.Members), len(record.DirectUsers))
+	return nil
+}
+
+// membershipCount содержит число участников роли через подгруппу и напрямую
+type membershipCount struct {
+	groupMembers int
+	directUsers  int
+}
+
+// renameRole переименовывает клиентскую роль и её подгруппу, проверяя сохранность участников.
+// Keycloak хранит назначения ролей по ID, поэтому переименование не затрагивает участников.
+// При ошибке после переименования прежнее имя восстанавливается
+func (app *Operation) renameRole(roleId, subGroupId string) error {
+	newName := app.targetRoleName
+	if app.findRole(newName, false) != "" || (app.parentGroupId != "" && app.getSubGroupByName(newName) != "") {
+		return fmt.Errorf("роль или подгруппа %s уже существует, переименование %s отменено", newName, app.roleName)
+	}
+
+	before, err := app.countMembership(app.roleName, subGroupId)
+	if err != nil {
+		return fmt.Errorf("не удалось подсчитать участников роли %s, переименование отменено", app.roleName)
+	}
+
+	if !app.updateName(clientRoleEndpoint, map[string]string{"clientId": app.clientId, "role": app.roleName}, newName) {
+		return fmt.Errorf("роль %s не переименована", app.roleName)
+	}
+	if subGroupId != "" && !app.updateName(groupEndpoint, map[string]string{"groupId": subGroupId}, newName) {
+		app.rollbackRename("", newName)
+		return fmt.Errorf("подгруппа роли %s не переименована", app.roleName)
+	}
+
+	if renamedId := app.findRole(newName, true); renamedId != roleId {
+		app.rollbackRename(subGroupId, newName)
+		return fmt.Errorf("после переименования роль %s имеет ID %s вместо %s", newName, renamedId, roleId)
+	}
+
+	after, err := app.countMembership(newName, subGroupId)
+	if err != nil {
+		app.rollbackRename(subGroupId, newName)
+		return fmt.Errorf("не удалось проверить участников роли %s после переименования", newName)
+	}
+	if after != before {
+		app.rollbackRename(subGroupId, newName)
+		return fmt.Errorf("число участников роли %s изменилось после переименования: было %d в подгруппе и %d напрямую, стало %d и %d",
+			newName, before.groupMembers, before.directUsers, after.groupMembers, after.directUsers)
+	}
+
+	logInfo("Роль %s клиента %s переименована в %s, участников в подгруппе: %d, напрямую: %d",
+		app.roleName, app.ClientIdName, newName, after.groupMembers, after.directUsers)
+	return nil
+}
+
+// rollbackRename возвращает роли и подгруппе (если она уже переименована) прежнее имя,
+// чтобы после неудачного переименования их имена не расходились
+func (app *Operation) rollbackRename(subGroupId, newName string) {
+	restored := app.updateName(clientRoleEndpoint, map[string]string{"clientId": app.clientId, "role": newName}, app.roleName)
+	if subGroupId != "" {
+		restored = app.updateName(groupEndpoint, map[string]string{"groupId": subGroupId}, app.roleName) && restored
+	}
+	if !restored {
+		app.AddError(fmt.Sprintf("Не удалось вернуть прежнее имя %s после неудачного переименования в %s: "+
+			"проверьте роль и подгруппу вручную", app.roleName, newName))
+		return
+	}
+	logWarn("Переименование %s в %s отменено, прежнее имя восстановлено", app.roleName, newName)
+}
+
+// countMembership подсчитывает участников подгруппы роли и пользователей с прямым назначением роли
+func (app *Operation) countMembership(roleName, subGroupId string) (membershipCount, error) {
+	var count membershipCount
+
+	if subGroupId != "" {
+		members, err := app.getGroupMembers(subGroupId)
+		if err != nil {
+			return count, err
+		}
+		count.groupMembers = len(members)
+	}
+
+	users, err := app.getRoleUsers(roleName)
+	if err != nil {
+		return count, err
+	}
+	count.directUsers = len(users)
+	return count, nil
+}
+
+// updateName меняет поле name в представлении ресурса, сохраняя остальные поля
+func (app *Operation) updateName(endpoint string, params map[string]string, newName string) bool {
+	raw := app.fetchRaw(endpoint, params)
+	if raw == nil {
+		app.AddError(fmt.Sprintf("Не удалось получить %s (%v) для переименования", endpoint, params))
+		return false
+	}
+
+	var representation map[string]interface{}
+	if err := json.Unmarshal(raw, &representation); err != nil {
+		app.AddError(fmt.Sprintf("Ошибка парсинга %s (%v): %v", endpoint, params, err))
+		return false
+	}
+	representation["name"] = newName
+
+	res, err := app.client.R().
+		SetBody(representation).
+		SetHeader("Content-Type", "application/json").
+		SetPathParam("instance", app.realm).
+		SetPathParams(params).
+		Put(endpoint)
+
+	if err != nil || res.StatusCode() != http.StatusNoContent {
+		app.AddError(fmt.Sprintf("Ошибка переименования %s (%v) в %s: %s, статус: %d",
+			endpoint, params, newName, res.String(), res.StatusCode()))
+		return false
+	}
+	return true
+}
+
+// confirmRoleDeletion проверяет, что у роли нет участников или её удаление подтверждено опцией confirm
+func (app *Operation) confirmRoleDeletion(members, directUsers []string) error {
+	if len(members) == 0 && len(directUsers) == 0 {
+		return nil
+	}
+
+	logInfo("Участники роли %s: в подгруппе (%d): %s; с прямым назначением (%d): %s", app.roleName,
+		len(members), strings.Join(members, ", "), len(directUsers), strings.Join(directUsers, ", "))
+	if app.hasOption(optionConfirm) {
+		return nil
+	}
+	return fmt.Errorf("роль %s содержит участников: в подгруппе %d, с прямым назначением %d. "+
+		"Для удаления укажите опцию '%s' в колонке Options", app.roleName, len(members), len(directUsers), optionConfirm)
+}
+
+// fetchRaw запрашивает ресурс Keycloak и возвращает тело ответа без разбора, nil если ресурс недоступен
+func (app *Operation) fetchRaw(endpoint string, params map[string]string) json.RawMessage {
+	res, err := app.client.R().
+		SetPathParam("instance", app.realm).
+		SetPathParams(params).
+		Get(endpoint)
+
+	if err != nil || res.StatusCode() != http.StatusOK {
+		return nil
+	}
+	return json.RawMessage(res.Body())
+}
+
+// deleteResource удаляет ресурс Keycloak и регистрирует ошибку при неудаче
+func (app *Operation) deleteResource(endpoint string, params map[string]string) bool {
+	res, err := app.client.R().
+		SetPathParam("instance", app.realm).
+		SetPathParams(params).
+		Delete(endpoint)
+
+	if err != nil || res.StatusCode() != http.StatusNoContent {
+		app.AddError(fmt.Sprintf("Ошибка удаления %s (%v): %s, статус: %d",
+			endpoint, params, res.String(), res.StatusCode()))
+		return false
+	}
+	return true
+}
+
+// sanitizeFileName заменяет символы, недопустимые в именах файлов
+func sanitizeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`<>:"/\|?* `, r) {
+			return '_'
+		}
+		return r
+	}, name)
+}
@@ -0,0 +1,149 @@
+// server_info.go определяет версию сервера Keycloak и доступные варианты API
+//   - Старые версии (до 17) работают под префиксом /auth
+//   - До 23 версии подгруппы встроены в представление группы, эндпоинта children нет
+//   - Параметр briefRepresentation поддерживается не во всех версиях
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	serverInfoEndpoint       = "/admin/serverinfo"
+	legacyPathPrefix         = "/auth"
+	childrenEndpointSince    = 23
+	briefRepresentationSince = 12
+)
+
+// ServerInfo содержит версию Keycloak и выбранные по ней варианты эндпоинтов
+type ServerInfo struct {
+	Version             string // Версия сервера, например "24.0.5"
+	Major               int    // Мажорная версия, 0 если определить не удалось
+	LegacyPrefix        bool   // Сервер работает под префиксом /auth
+	ChildrenEndpoint    bool   // Есть эндпоинт /groups/{id}/children
+	BriefRepresentation bool   // Поддерживается параметр briefRepresentation
+}
+
+// serverInfoResponse представляет нужную часть ответа /admin/serverinfo
+type serverInfoResponse struct {
+	SystemInfo struct {
+		Version string `json:"version"`
+	} `json:"systemInfo"`
+}
+
+// serverInfoCache хранит сведения о серверах по исходному базовому URL, чтобы запрашивать их один раз
+var serverInfoCache = make(map[string]*ServerInfo)
+
+// newServerInfo определяет доступные варианты API по версии сервера
+func newServerInfo(version string, legacyPrefix bool) *ServerInfo {
+	major, _ := strconv.Atoi(strings.SplitN(version, ".", 2)[0])
+	info := &ServerInfo{Version: version, Major: major, LegacyPrefix: legacyPrefix}
+
+	// Неизвестная версия считается современной: так работал инструмент до определения версии
+	info.ChildrenEndpoint = major == 0 || major >= childrenEndpointSince
+	info.BriefRepresentation = major == 0 || major >= briefRepresentationSince
+	return info
+}
+
+// applyKnownServerPrefix переключает клиент на префикс /auth, если он уже определён для этого сервера
+func (app *Operation) applyKnownServerPrefix() {
+	if info, ok := serverInfoCache[app.serverKey()]; ok && info.LegacyPrefix {
+		app.useLegacyPrefix()
+	}
+}
+
+// useLegacyPrefix переключает базовый URL клиента на префикс /auth
+func (app *Operation) useLegacyPrefix() {
+	if !strings.HasSuffix(app.client.BaseURL, legacyPathPrefix) {
+		app.client.SetBaseURL(app.client.BaseURL + legacyPathPrefix)
+	}
+}
+
+// hasLegacyPrefix сообщает, работает ли клиент под префиксом /auth
+func (app *Operation) hasLegacyPrefix() bool {
+	return strings.HasSuffix(app.client.BaseURL, legacyPathPrefix)
+}
+
+// serverKey возвращает ключ кэша сведений о сервере - базовый URL без префикса /auth
+func (app *Operation) serverKey() string {
+	return strings.TrimSuffix(app.client.BaseURL, legacyPathPrefix)
+}
+
+// detectServer запрашивает версию сервера один раз для инстанса и сохраняет её в операции
+func (app *Operation) detectServer() {
+	key := app.serverKey()
+	if info, ok := serverInfoCache[key]; ok && info.Version != "" {
+		app.server = info
+		return
+	}
+
+	info := newServerInfo("", app.hasLegacyPrefix())
+	res, err := app.client.R().Get(serverInfoEndpoint)
+	if err == nil && res.StatusCode() == http.StatusOK {
+		var response serverInfoResponse
+		if err := json.Unmarshal(res.Body(), &response); err == nil {
+			info = newServerInfo(response.SystemInfo.Version, app.hasLegacyPrefix())
+		}
+	}
+
+	if info.Version == "" {
+		logWarn("Не удалось определить версию Keycloak %s, используются эндпоинты последних версий", key)
+	} else {
+		logInfo("Keycloak %s: версия %s, префикс /auth: %t, эндпоинт children: %t",
+			key, info.Version, info.LegacyPrefix, info.ChildrenEndpoint)
+	}
+
+	serverInfoCache[key] = info
+	app.server = info
+}
+
+// serverInfo возвращает сведения о сервере; до аутентификации - варианты последних версий
+func (app *Operation) serverInfo() *ServerInfo {
+	if app.server == nil {
+		return newServerInfo("", false)
+	}
+	return app.server
+}
+
+// setBriefRepresentation добавляет briefRepresentation в параметры, если сервер его поддерживает
+func (app *Operation) setBriefRepresentation(params map[string]string, brief bool) map[string]string {
+	if app.serverInfo().BriefRepresentation {
+		params["briefRepresentation"] = strconv.FormatBool(brief)
+	}
+	return params
+}
+
+// getEmbeddedSubgroups получает подгруппы из представления группы для версий без эндпоинта children
+func (app *Operation) getEmbeddedSubgroups(groupID, search string) ([]Group, error) {
+	res, err := app.client.R().
+		SetPathParam("realm", app.realm).
+		SetPathParam("groupId", groupID).
+		Get(groupsEndpoint + "/{groupId}")
+
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode() != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d: %s", res.StatusCode(), res.String())
+	}
+
+	var group Group
+	if err := json.Unmarshal(res.Body(), &group); err != nil {
+		return nil, err
+	}
+	if search == "" {
+		return group.SubGroups, nil
+	}
+
+	var matched []Group
+	for _, subgroup := range group.SubGroups {
+		if subgroup.Name == search {
+			matched = append(matched, subgroup)
+		}
+	}
+	return matched, nil
+}
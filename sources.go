@@ -0,0 +1,327 @@
+// sources.go содержит источники файлов запросов
+//   - Excel (.xlsx/.xls), LibreOffice (.ods), CSV, YAML и JSON превращаются в одинаковые таблицы "заголовок + строки"
+//   - Таблицы проходят одно и то же сопоставление колонок, валидацию и отчёты
+//   - Источник выбирается по расширению файла или явно указанному формату
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RequestTable содержит строки запросов одного листа или файла
+type RequestTable struct {
+	Name     string          // Имя листа или файла для сообщений
+	Header   []string        // Заголовки колонок
+	Rows     [][]string      // Строки данных
+	FirstRow int             // Номер первой строки данных для сообщений
+	Sheets   []workbookSheet // Все листы книги для ссылок на списки логинов; nil для CSV, YAML и JSON
+	Template templateStamp   // Версия шаблона книги
+}
+
+// RequestSource читает файл запросов в таблицы
+type RequestSource interface {
+	Read(path string) ([]RequestTable, error)
+}
+
+// requestSources сопоставляет формат (расширение без точки) источнику
+var requestSources = map[string]RequestSource{
+	"xlsx": excelSource{},
+	"xls":  xlsSource{},
+	"ods":  odsSource{},
+	"csv":  csvSource{},
+	"yaml": yamlSource{},
+	"yml":  yamlSource{},
+	"json": jsonSource{},
+}
+
+// ignoredRequestFiles содержит файлы рядом с исполняемым файлом, которые не являются запросами
+var ignoredRequestFiles = map[string]bool{
+	configFileName:        true,
+	"config.example.yaml": true,
+	"auth.yaml":           true,
+}
+
+// requestFormat возвращает формат файла: явно заданный или по расширению
+func requestFormat(path, format string) string {
+	if format != "" {
+		return strings.ToLower(format)
+	}
+	return strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+}
+
+// isRequestFile проверяет, можно ли обработать файл как файл запросов
+func isRequestFile(path string) bool {
+	name := filepath.Base(path)
+	if ignoredRequestFiles[strings.ToLower(name)] || strings.HasPrefix(name, "~$") {
+		return false
+	}
+	if aliases := config.Normalize.AliasesFile; aliases != "" && strings.EqualFold(name, filepath.Base(aliases)) {
+		return false
+	}
+	_, ok := requestSources[requestFormat(path, "")]
+	return ok
+}
+
+// readRequestFile читает файл запросов любого поддерживаемого формата и преобразует его в операции
+func readRequestFile(path, format string) ([]Operation, error) {
+	source, ok := requestSources[requestFormat(path, format)]
+	if !ok {
+		return nil, fmt.Errorf("неподдерживаемый формат файла %s", filepath.Base(path))
+	}
+
+	tables, err := source.Read(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var operations []Operation
+	for _, table := range tables {
+		layout, err := table.columnLayout()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", table.Name, err)
+		}
+		operations = append(operations, processRequestRows(layout, table, path)...)
+	}
+	return operations, nil
+}
+
+// excelSource читает листы запросов Excel
+type excelSource struct{}
+
+// Read читает листы запросов книги Excel
+func (excelSource) Read(path string) ([]RequestTable, error) {
+	config := newExcelConfig(path)
+	sheets, stamp, err := readExcelSheets(config)
+	if err != nil {
+		return nil, err
+	}
+
+	tables, err := requestSheetTables(config, sheets)
+	for i := range tables {
+		tables[i].Template = stamp
+	}
+	return tables, err
+}
+
+// workbookSheet содержит имя и ячейки листа книги
+type workbookSheet struct {
+	Name string
+	Rows [][]string
+}
+
+// requestSheetTables выбирает листы запросов книги по шаблону и превращает их в таблицы.
+// Если листов несколько, пустой лист пропускается с предупреждением. Остальные листы книги
+// остаются доступны для ссылок на списки логинов
+func requestSheetTables(config ExcelConfig, sheets []workbookSheet) ([]RequestTable, error) {
+	names := make([]string, 0, len(sheets))
+	for _, sheet := range sheets {
+		names = append(names, sheet.Name)
+	}
+	matched, err := matchRequestSheets(config, names)
+	if err != nil {
+		return nil, err
+	}
+
+	var tables []RequestTable
+	for _, sheet := range sheets {
+		if !slices.Contains(matched, sheet.Name) {
+			continue
+		}
+		header, rows, err := splitSheetRows(config, sheet.Rows)
+		if err != nil {
+			if len(matched) > 1 {
+				logWarn("Лист %s пропущен: %v", sheet.Name, err)
+				continue
+			}
+			return nil, err
+		}
+		tables = append(tables, RequestTable{Name: sheet.Name, Header: header, Rows: rows,
+			FirstRow: config.HeaderRows + 1, Sheets: sheets})
+	}
+	if len(tables) == 0 {
+		return nil, fmt.Errorf("файл не содержит данных для обработки")
+	}
+	return tables, nil
+}
+
+// tableSource возвращает имя файла для отчётов; для листов, кроме стандартного Request, добавляется имя листа
+func tableSource(path string, table RequestTable) string {
+	name := filepath.Base(path)
+	if table.Name == name || table.Name == excelSheetName {
+		return name
+	}
+	return name + " [" + table.Name + "]"
+}
+
+// setSheetCell записывает значение ячейки, расширяя таблицу до нужного размера
+func setSheetCell(rows [][]string, row, col int, value string) [][]string {
+	for len(rows) <= row {
+		rows = append(rows, nil)
+	}
+	for len(rows[row]) <= col {
+		rows[row] = append(rows[row], "")
+	}
+	rows[row][col] = value
+	return rows
+}
+
+// csvSource читает CSV-файл с заголовком; разделитель "," или ";" определяется по заголовку
+type csvSource struct{}
+
+// Read читает CSV-файл
+func (csvSource) Read(path string) ([]RequestTable, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка открытия файла: %w", err)
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	firstLine, _, _ := strings.Cut(string(data), "\n")
+	if strings.Count(firstLine, ";") > strings.Count(firstLine, ",") {
+		reader.Comma = ';'
+	}
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения CSV: %w", err)
+	}
+	if len(records) < 2 {
+		return nil, fmt.Errorf("файл не содержит данных для обработки")
+	}
+	return []RequestTable{{Name: filepath.Base(path), Header: records[0], Rows: records[1:], FirstRow: 2}}, nil
+}
+
+// yamlSource читает YAML-файл со списком запросов
+type yamlSource struct{}
+
+// Read читает YAML-файл: список объектов или объект с ключом requests
+func (yamlSource) Read(path string) ([]RequestTable, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка открытия файла: %w", err)
+	}
+
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("ошибка разбора YAML: %w", err)
+	}
+	if len(document.Content) == 0 {
+		return nil, fmt.Errorf("файл не содержит данных для обработки")
+	}
+
+	list := document.Content[0]
+	if list.Kind == yaml.MappingNode {
+		list = yamlMappingValue(list, requestsKey)
+	}
+	if list == nil || list.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("ожидается список запросов или ключ %s со списком", requestsKey)
+	}
+
+	var records []orderedRecord
+	for _, item := range list.Content {
+		if item.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("строка %d: ожидается объект с полями запроса", item.Line)
+		}
+		var record orderedRecord
+		for i := 0; i+1 < len(item.Content); i += 2 {
+			value, err := yamlScalarValue(item.Content[i+1])
+			if err != nil {
+				return nil, fmt.Errorf("строка %d: %w", item.Content[i+1].Line, err)
+			}
+			record = append(record, recordField{key: item.Content[i].Value, value: value})
+		}
+		records = append(records, record)
+	}
+	return []RequestTable{recordsToTable(filepath.Base(path), records)}, nil
+}
+
+// jsonSource читает JSON-файл со списком запросов
+type jsonSource struct{}
+
+// Read читает JSON-файл: массив объектов или объект с ключом requests.
+// JSON - подмножество YAML, поэтому используется тот же разбор с сохранением порядка полей
+func (jsonSource) Read(path string) ([]RequestTable, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка открытия файла: %w", err)
+	}
+	if !json.Valid(data) {
+		return nil, fmt.Errorf("файл %s не является корректным JSON", filepath.Base(path))
+	}
+	return yamlSource{}.Read(path)
+}
+
+// requestsKey - ключ со списком запросов в YAML/JSON
+const requestsKey = "requests"
+
+// recordField содержит поле записи YAML/JSON
+type recordField struct {
+	key   string
+	value string
+}
+
+// orderedRecord содержит поля записи в порядке их следования в файле
+type orderedRecord []recordField
+
+// recordsToTable превращает записи в таблицу: заголовки - все ключи в порядке первого появления
+func recordsToTable(name string, records []orderedRecord) RequestTable {
+	table := RequestTable{Name: name, FirstRow: 1}
+	positions := make(map[string]int)
+	for _, record := range records {
+		for _, field := range record {
+			if _, ok := positions[field.key]; !ok {
+				positions[field.key] = len(table.Header)
+				table.Header = append(table.Header, field.key)
+			}
+		}
+	}
+
+	for _, record := range records {
+		row := make([]string, len(table.Header))
+		for _, field := range record {
+			row[positions[field.key]] = field.value
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	return table
+}
+
+// yamlMappingValue возвращает значение ключа в YAML-объекте
+func yamlMappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// yamlScalarValue возвращает значение поля; список (например, логинов) объединяется через запятую
+func yamlScalarValue(node *yaml.Node) (string, error) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return node.Value, nil
+	case yaml.SequenceNode:
+		values := make([]string, 0, len(node.Content))
+		for _, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				return "", fmt.Errorf("ожидается список значений")
+			}
+			values = append(values, item.Value)
+		}
+		return strings.Join(values, ", "), nil
+	default:
+		return "", fmt.Errorf("ожидается значение или список значений")
+	}
+}
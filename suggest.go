@@ -0,0 +1,172 @@
+// suggest.go подбирает похожих пользователей для ненайденных логинов
+//   - Выполняет неточный поиск по логину, e-mail и фамилии
+//   - Ранжирует кандидатов по расстоянию Левенштейна
+//   - Только сообщает о кандидатах, никогда не назначает их автоматически
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	maxSuggestions      = 3  // Сколько похожих пользователей показывать
+	suggestionSearchMax = 20 // Сколько кандидатов запрашивать в одном поиске
+	suggestionPrefixLen = 3  // Длина префикса для поиска при опечатке в середине логина
+)
+
+// suggestion содержит похожего пользователя и его удалённость от искомого логина
+type suggestion struct {
+	user     UserDetails
+	distance int
+}
+
+// reportSuggestions регистрирует ненайденный логин вместе с похожими пользователями
+func (app *Operation) reportSuggestions(ldap, value string) {
+	suggestions := app.findSuggestions(value)
+	if len(suggestions) == 0 {
+		app.AddError(fmt.Sprintf("LDAP не найден: %s", ldap))
+		app.addOutcome(ldap, "не найден", "")
+		return
+	}
+
+	described := make([]string, 0, len(suggestions))
+	for _, s := range suggestions {
+		described = append(described, describeSuggestion(s.user))
+	}
+	hint := "возможно, имелся в виду: " + strings.Join(described, "; ")
+
+	app.AddError(fmt.Sprintf("LDAP не найден: %s, %s", ldap, hint))
+	app.addOutcome(ldap, "не найден", hint)
+}
+
+// findSuggestions ищет пользователей, похожих на значение, и возвращает ближайших
+func (app *Operation) findSuggestions(value string) []suggestion {
+	if value == "" {
+		return nil
+	}
+
+	queries := []map[string]string{
+		{"search": value},
+		{"lastName": value, "exact": "false"},
+	}
+	if local, _, found := strings.Cut(value, "@"); found && local != "" {
+		queries = append(queries, map[string]string{"search": local})
+	}
+	if runes := []rune(value); len(runes) > suggestionPrefixLen {
+		queries = append(queries, map[string]string{"search": string(runes[:suggestionPrefixLen])})
+	}
+
+	candidates := make(map[string]UserDetails)
+	for _, query := range queries {
+		if err := app.checkRateLimit(value); err != nil {
+			break
+		}
+		for _, user := range app.searchCandidates(query) {
+			candidates[user.ID] = user
+		}
+	}
+
+	suggestions := make([]suggestion, 0, len(candidates))
+	for _, user := range candidates {
+		suggestions = append(suggestions, suggestion{user: user, distance: candidateDistance(value, user)})
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].distance != suggestions[j].distance {
+			return suggestions[i].distance < suggestions[j].distance
+		}
+		return suggestions[i].user.Username < suggestions[j].user.Username
+	})
+
+	// Слишком далёкие кандидаты только путают: допускаем правку не более трети длины логина
+	threshold := len([]rune(value))/3 + 1
+	var closest []suggestion
+	for _, s := range suggestions {
+		if s.distance > threshold || len(closest) == maxSuggestions {
+			break
+		}
+		closest = append(closest, s)
+	}
+	return closest
+}
+
+// searchCandidates выполняет неточный поиск пользователей; ошибки не регистрируются, это лишь подсказка
+func (app *Operation) searchCandidates(query map[string]string) []UserDetails {
+	query["max"] = strconv.Itoa(suggestionSearchMax)
+
+	resp, err := app.client.R().
+		SetPathParam("instance", app.realm).
+		SetQueryParams(app.setBriefRepresentation(query, true)).
+		Get(usersEndpoint)
+	if err != nil || resp.StatusCode() != http.StatusOK {
+		return nil
+	}
+
+	var users []UserDetails
+	if err := json.Unmarshal(resp.Body(), &users); err != nil {
+		return nil
+	}
+	return users
+}
+
+// candidateDistance возвращает минимальное расстояние от значения до логина, e-mail или фамилии кандидата
+func candidateDistance(value string, user UserDetails) int {
+	value = strings.ToLower(value)
+	best := levenshtein(value, strings.ToLower(user.Username))
+
+	fields := []string{user.Email, user.LastName}
+	if local, _, found := strings.Cut(user.Email, "@"); found {
+		fields = append(fields, local)
+	}
+	for _, field := range fields {
+		if field == "" {
+			continue
+		}
+		if d := levenshtein(value, strings.ToLower(field)); d < best {
+			best = d
+		}
+	}
+	return best
+}
+
+// describeSuggestion форматирует кандидата для отчёта: логин (Имя Фамилия, e-mail)
+func describeSuggestion(user UserDetails) string {
+	var details []string
+	if name := strings.TrimSpace(user.FirstName + " " + user.LastName); name != "" {
+		details = append(details, name)
+	}
+	if user.Email != "" {
+		details = append(details, user.Email)
+	}
+	if len(details) == 0 {
+		return user.Username
+	}
+	return fmt.Sprintf("%s (%s)", user.Username, strings.Join(details, ", "))
+}
+
+// levenshtein вычисляет расстояние редактирования между строками
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,89 @@
+// targets.go разбирает колонки Keycloak type и Keycloak environment со списком целей
+//   - В ячейке можно указать несколько значений через запятую, точку с запятой или перевод строки, либо All
+//   - Строка разворачивается в операцию на каждую пару инстанс/окружение, окружения - по порядку Dev, Test, Prod
+//   - Итог каждой цели выводится в лог и отчёт отдельно
+//   - С опцией stop-before-prod операция строки на Prod пропускается, если на младшем окружении была ошибка
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// allTargetsMarker в колонке типа или окружения означает все допустимые значения
+const allTargetsMarker = "All"
+
+// prodEnvironment - окружение, на которое с опцией stop-before-prod изменения не выкатываются после ошибки
+const prodEnvironment = "Prod"
+
+// environmentOrder задаёт порядок выполнения операций строки по окружениям
+var environmentOrder = []string{"Dev", "Test", prodEnvironment}
+
+// instanceOrder задаёт порядок выполнения операций строки по инстансам
+var instanceOrder = strings.Split(validTypes, "|")
+
+// parseTargets разбирает ячейку со списком целей и возвращает значения в порядке ordered
+func parseTargets(cell string, ordered []string) ([]string, error) {
+	if slices.ContainsFunc(parseLDAPs(cell), func(value string) bool {
+		return strings.EqualFold(value, allTargetsMarker)
+	}) {
+		return slices.Clone(ordered), nil
+	}
+
+	values, err := parseTargetList(cell, strings.Join(ordered, "|"))
+	if err != nil {
+		return nil, fmt.Errorf("%w или %s", err, allTargetsMarker)
+	}
+	var targets []string
+	for _, value := range ordered {
+		if slices.Contains(values, value) {
+			targets = append(targets, value)
+		}
+	}
+	return targets, nil
+}
+
+// rowTargets возвращает пары инстанс/окружение строки: по инстансам, внутри инстанса - по окружениям
+func rowTargets(types, environments string) ([][2]string, error) {
+	instances, err := parseTargets(types, instanceOrder)
+	if err != nil {
+		return nil, fmt.Errorf("неверный тип Keycloak: %w", err)
+	}
+	envs, err := parseTargets(environments, environmentOrder)
+	if err != nil {
+		return nil, fmt.Errorf("неверное окружение: %w", err)
+	}
+
+	targets := make([][2]string, 0, len(instances)*len(envs))
+	for _, instance := range instances {
+		for _, env := range envs {
+			targets = append(targets, [2]string{instance, env})
+		}
+	}
+	return targets, nil
+}
+
+// targetGroup возвращает ключ строки и инстанса: операции с одним ключом - одна строка, развёрнутая по окружениям
+func (o *Operation) targetGroup() string {
+	return fmt.Sprintf("%s|%d|%s", o.sourceFile, o.rowNum, o.instance)
+}
+
+// blockedByLowerEnvironment сообщает, что операцию на Prod нужно пропустить из-за ошибки на младшем окружении строки
+func (o *Operation) blockedByLowerEnvironment(failedGroups map[string]bool) bool {
+	return o.environment == prodEnvironment && o.hasOption(optionStopBeforeProd) && failedGroups[o.targetGroup()]
+}
+
+// hasFailed сообщает, завершилась ли операция ошибкой: операция прервана или хотя бы один
+// пользователь не обработан. Замечания в errors (например, о стратегии поиска) ошибкой не считаются
+func (o *Operation) hasFailed() bool {
+	if o.failed {
+		return true
+	}
+	for _, outcome := range o.outcomes {
+		if outcome.Status == outcomeFailed {
+			return true
+		}
+	}
+	return false
+}
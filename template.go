@@ -0,0 +1,287 @@
+// template.go реализует команду template
+//   - Создаёт новую книгу запросов с листом Request и выпадающими списками в колонках
+//   - Типы, окружения и действия берутся из допустимых значений, клиенты - с живых инстансов (-clients)
+//   - Лист Instructions описывает заполнение, версия шаблона записывается в свойства документа
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/xuri/excelize/v2"
+)
+
+// Параметры генерируемого шаблона
+const (
+	templateVersion       = 1
+	templateFileName      = "RequestTemplate.xlsx"
+	instructionsSheetName = "Instructions"
+	valuesSheetName       = "Values"
+	templateRowsCount     = 500 // строк листа Request с выпадающими списками
+)
+
+// runTemplate выполняет команду template
+func (a *App) runTemplate(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("template", flag.ContinueOnError)
+	var target targetFlags
+	target.register(fs)
+	output := fs.String("output", templateFileName, "файл шаблона")
+	withClients := fs.Bool("clients", false, "заполнить список клиентов с инстансов из -types и -envs")
+	force := fs.Bool("force", false, "перезаписать существующий файл")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Использование: template [параметры]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(*output); err == nil && !*force {
+		return fmt.Errorf("файл %s уже существует, укажите другой -output или -force", *output)
+	}
+
+	var clients []string
+	if *withClients {
+		operations, err := target.targets()
+		if err != nil {
+			return err
+		}
+		if clients, err = collectClientIDs(ctx, operations); err != nil {
+			return err
+		}
+	}
+
+	if err := writeRequestTemplate(*output, a.version, clients); err != nil {
+		return err
+	}
+	logInfo("Шаблон версии %d сохранён в %s", templateVersion, *output)
+	return nil
+}
+
+// collectClientIDs собирает отсортированный список clientId со всех выбранных инстансов
+func collectClientIDs(ctx context.Context, operations []Operation) ([]string, error) {
+	var clients []string
+	for i := range operations {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		operation := &operations[i]
+		if err := operation.Authenticate(); err != nil {
+			logWarn("%s/%s: клиенты не получены: %v", operation.instance, operation.environment, err)
+			continue
+		}
+		ids, err := operation.fetchClientIDs()
+		if err != nil {
+			logWarn("%s/%s: клиенты не получены: %v", operation.instance, operation.environment, err)
+			continue
+		}
+		logInfo("%s/%s: клиентов %d", operation.instance, operation.environment, len(ids))
+		clients = append(clients, ids...)
+	}
+
+	slices.Sort(clients)
+	return slices.Compact(clients), nil
+}
+
+// fetchClientIDs возвращает clientId всех клиентов realm
+func (app *Operation) fetchClientIDs() ([]string, error) {
+	clients, err := fetchAllPages[Client](func(first, max int) (*resty.Response, error) {
+		return app.client.R().
+			SetPathParam("instance", app.realm).
+			SetQueryParams(map[string]string{
+				"first": strconv.Itoa(first),
+				"max":   strconv.Itoa(max),
+			}).
+			Get(clientsEndpoint)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(clients))
+	for _, client := range clients {
+		ids = append(ids, client.ClientID)
+	}
+	return ids, nil
+}
+
+// templateColumn описывает колонку листа Request шаблона
+type templateColumn struct {
+	key    string   // Ключ колонки из requestColumns
+	values []string // Значения выпадающего списка; пусто - без списка
+	strict bool     // Запрещать значения не из списка
+	width  float64
+}
+
+// writeRequestTemplate создаёт книгу шаблона
+func writeRequestTemplate(path, appVersion string, clients []string) error {
+	columns := []templateColumn{
+		{key: columnType, values: append(slices.Clone(instanceOrder), allTargetsMarker), width: 18},
+		{key: columnEnvironment, values: append(slices.Clone(environmentOrder), allTargetsMarker), width: 22},
+		{key: columnAction, values: strings.Split(validActions, "|"), strict: true, width: 44},
+		{key: columnClient, values: clients, width: 28},
+		{key: columnRole, width: 28},
+		{key: columnLogins, width: 40},
+		{key: columnOptions, width: 24},
+	}
+
+	f := excelize.NewFile()
+	defer closeExcelFile(f)
+
+	if err := f.SetSheetName(f.GetSheetName(0), excelSheetName); err != nil {
+		return err
+	}
+	if err := writeTemplateRequest(f, columns); err != nil {
+		return err
+	}
+	if err := writeTemplateInstructions(f, len(clients) > 0); err != nil {
+		return err
+	}
+	if err := writeTemplateValues(f, columns); err != nil {
+		return err
+	}
+	if err := f.SetDocProps(&excelize.DocProperties{
+		Title:    "Keycloak role request",
+		Creator:  "KeycloakRolesConfigurator " + appVersion,
+		Version:  strconv.Itoa(templateVersion),
+		Keywords: templateMarker,
+		Created:  time.Now().UTC().Format(time.RFC3339),
+	}); err != nil {
+		return err
+	}
+
+	f.SetActiveSheet(0)
+	if err := f.SaveAs(path); err != nil {
+		return fmt.Errorf("ошибка сохранения шаблона %s: %w", path, err)
+	}
+	return nil
+}
+
+// writeTemplateRequest заполняет лист Request: заголовки, ширина колонок и выпадающие списки
+func writeTemplateRequest(f *excelize.File, columns []templateColumn) error {
+	bold, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return err
+	}
+
+	header := make([]interface{}, 0, len(columns))
+	for _, column := range columns {
+		header = append(header, defaultColumnSynonyms[column.key][0])
+	}
+	if err := f.SetSheetRow(excelSheetName, "A1", &header); err != nil {
+		return err
+	}
+	lastHeader, _ := excelize.CoordinatesToCellName(len(columns), 1)
+	if err := f.SetCellStyle(excelSheetName, "A1", lastHeader, bold); err != nil {
+		return err
+	}
+	if err := f.SetPanes(excelSheetName, &excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"}); err != nil {
+		return err
+	}
+
+	for i, column := range columns {
+		name, _ := excelize.ColumnNumberToName(i + 1)
+		if err := f.SetColWidth(excelSheetName, name, name, column.width); err != nil {
+			return err
+		}
+		if len(column.values) == 0 {
+			continue
+		}
+
+		dv := excelize.NewDataValidation(true)
+		dv.SetSqref(fmt.Sprintf("%s2:%s%d", name, name, templateRowsCount+1))
+		dv.SetSqrefDropList(fmt.Sprintf("%s!$%s$2:$%s$%d", valuesSheetName, name, name, len(column.values)+1))
+		if column.strict {
+			dv.SetError(excelize.DataValidationErrorStyleStop, "Недопустимое значение", "Выберите значение из списка")
+		} else {
+			dv.SetError(excelize.DataValidationErrorStyleWarning, "Значения нет в списке",
+				"Значение не найдено в списке. Продолжить?")
+		}
+		if err := f.AddDataValidation(excelSheetName, dv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeTemplateValues заполняет скрытый лист Values значениями выпадающих списков
+func writeTemplateValues(f *excelize.File, columns []templateColumn) error {
+	if _, err := f.NewSheet(valuesSheetName); err != nil {
+		return err
+	}
+	for i, column := range columns {
+		if len(column.values) == 0 {
+			continue
+		}
+		cell, _ := excelize.CoordinatesToCellName(i+1, 1)
+		values := append([]interface{}{defaultColumnSynonyms[column.key][0]}, toInterfaces(column.values)...)
+		if err := f.SetSheetCol(valuesSheetName, cell, &values); err != nil {
+			return err
+		}
+	}
+	return f.SetSheetVisible(valuesSheetName, false)
+}
+
+// writeTemplateInstructions заполняет лист Instructions
+func writeTemplateInstructions(f *excelize.File, withClients bool) error {
+	if _, err := f.NewSheet(instructionsSheetName); err != nil {
+		return err
+	}
+
+	clientHint := "Client ID - clientId клиента Keycloak"
+	if withClients {
+		clientHint += " (список получен с инстансов при создании шаблона, другие значения допустимы с предупреждением)"
+	}
+	lines := []string{
+		"Заявка на роли Keycloak",
+		"Версия шаблона: " + strconv.Itoa(templateVersion),
+		"",
+		"Заполните лист " + excelSheetName + ": одна строка - одно действие с ролью.",
+		"Action - выберите значение из выпадающего списка.",
+		"Keycloak type, Keycloak environment - значение из списка, несколько значений через запятую или " +
+			allTargetsMarker + " - строка выполняется для каждой пары по порядку " + strings.Join(environmentOrder, ", ") + ".",
+		clientHint + ".",
+		"Role name - имя клиентской роли. Для " + actionRename + ", " + actionCopyMembers + " и " + actionMoveMembers +
+			" укажите пару: старая " + rolePairSeparator + " новая.",
+		"  " + bundlePrefix + "имя - набор ролей из config.yaml для " + actionCreate + ", " + actionAssociate + " и " +
+			actionRemove + "; Client ID не заполняется.",
+		"User logins - логины через запятую, точку с запятой или с новой строки; " + sheetReferencePrefix +
+			"Users - список с листа Users. Для " + actionCopyMembers + " и " + actionMoveMembers + " " + allMembersMarker +
+			" - все участники. Для " + actionDelete + " и " + actionRename + " не заполняется.",
+		"Options - необязательные опции через запятую:",
+		"  " + optionConfirm + " - подтвердить удаление роли с участниками",
+		"  " + optionCreateTarget + " - создать целевую роль при копировании и переносе участников",
+		"  " + optionImportUsers + " - импортировать ненайденных пользователей из федерации",
+		"  " + optionStopBeforeProd + " - не выполнять строку на " + prodEnvironment + ", если на младшем окружении была ошибка",
+		"  " + identifierOptionPrefix + "email - искать пользователей по e-mail (также username, attribute, auto)",
+		"",
+		"Действия:",
+	}
+	for _, action := range strings.Split(validActions, "|") {
+		lines = append(lines, "  "+action)
+	}
+
+	for i, line := range lines {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		if err := f.SetCellStr(instructionsSheetName, cell, line); err != nil {
+			return err
+		}
+	}
+	return f.SetColWidth(instructionsSheetName, "A", "A", 120)
+}
+
+// toInterfaces преобразует строки для записи строки или колонки Excel
+func toInterfaces(values []string) []interface{} {
+	result := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		result = append(result, value)
+	}
+	return result
+}
@@ -0,0 +1,123 @@
+// template_versions.go содержит историю раскладок шаблона файла запросов
+//   - Версия шаблона читается из свойств документа, которые записывает команда template
+//   - Версии 0 и 1 отличаются только колонкой Options, порядок остальных колонок одинаков
+//   - Если заголовки не распознаны, колонки книги с отметкой версии берутся по позициям раскладки,
+//     а validate отмечает это ошибкой. Книга без отметки с нераспознанными заголовками не обрабатывается
+//   - Отметка версии есть только в xlsx; остальные форматы сопоставляются как книги без отметки
+//   - Файлы новее программы отклоняются
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// templateMarker записывается в ключевые слова документа и отличает шаблоны программы от других книг
+const templateMarker = "KeycloakRolesConfigurator"
+
+// unmarkedTemplateVersion - версия книги без отметки, исходного AuthorizationTemplate.xlsx
+const unmarkedTemplateVersion = 0
+
+// templateLayout описывает колонки листа Request одной версии шаблона
+type templateLayout struct {
+	version int
+	columns []string // Ключи колонок в порядке листа
+	note    string   // Чем версия отличается от предыдущей
+}
+
+// templateLayouts перечисляет раскладки всех выпущенных версий шаблона, от старой к новой
+var templateLayouts = []templateLayout{
+	{
+		version: 0,
+		columns: []string{columnType, columnEnvironment, columnAction, columnClient, columnRole, columnLogins},
+		note:    "исходный AuthorizationTemplate.xlsx, без колонки Options",
+	},
+	{
+		version: 1,
+		columns: []string{columnType, columnEnvironment, columnAction, columnClient, columnRole, columnLogins, columnOptions},
+		note:    "шаблон команды template: колонка Options, выпадающие списки",
+	},
+}
+
+// templateStamp содержит версию шаблона, по которому создан файл запросов
+type templateStamp struct {
+	version int
+	marked  bool // Версия прочитана из отметки в книге, а не принята по умолчанию
+}
+
+// readTemplateStamp читает версию шаблона из свойств документа; книга без отметки - версия 0
+func readTemplateStamp(f *excelize.File) (templateStamp, error) {
+	props, err := f.GetDocProps()
+	if err != nil || !strings.Contains(props.Keywords, templateMarker) || props.Version == "" {
+		return templateStamp{version: unmarkedTemplateVersion}, nil
+	}
+
+	version, err := strconv.Atoi(strings.TrimSpace(props.Version))
+	if err != nil {
+		return templateStamp{}, fmt.Errorf("неизвестная версия шаблона %q", props.Version)
+	}
+	return templateStamp{version: version, marked: true}, nil
+}
+
+// checkTemplateVersion отклоняет файлы шаблона новее программы
+func checkTemplateVersion(version int) error {
+	if version > templateVersion {
+		return fmt.Errorf("файл создан шаблоном версии %d, программа поддерживает версии до %d: обновите программу",
+			version, templateVersion)
+	}
+	return nil
+}
+
+// findTemplateLayout возвращает раскладку колонок версии шаблона
+func findTemplateLayout(version int) (templateLayout, bool) {
+	for _, layout := range templateLayouts {
+		if layout.version == version {
+			return layout, true
+		}
+	}
+	return templateLayout{}, false
+}
+
+// columnLayout определяет колонки таблицы по заголовкам. Если заголовки не распознаны, колонки книги
+// с отметкой версии берутся по раскладке этой версии. Для книги без отметки позиции колонок
+// ничем не подтверждены, поэтому возвращается ошибка
+func (t RequestTable) columnLayout() (*ColumnLayout, error) {
+	if err := checkTemplateVersion(t.Template.version); err != nil {
+		return nil, err
+	}
+
+	layout, err := newColumnLayout(t.Header)
+	if err == nil {
+		return layout, nil
+	}
+
+	if !t.Template.marked {
+		return nil, err
+	}
+	template, ok := findTemplateLayout(t.Template.version)
+	if !ok {
+		return nil, err
+	}
+
+	logWarn("%s: %v. Колонки взяты по раскладке шаблона версии %d (%s)", t.Name, err, template.version, template.note)
+	return newPositionalLayout(template.columns, t.Header), nil
+}
+
+// newPositionalLayout создаёт раскладку по позициям колонок; колонки правее раскладки
+// с непустым заголовком передаются в отчёт как дополнительные
+func newPositionalLayout(columns []string, header []string) *ColumnLayout {
+	layout := &ColumnLayout{positions: make(map[string]int), byPosition: true}
+	for i, key := range columns {
+		layout.positions[key] = i
+	}
+	for i := len(columns); i < len(header); i++ {
+		if title := strings.TrimSpace(header[i]); title != "" {
+			layout.extras = append(layout.extras, title)
+			layout.extraPos = append(layout.extraPos, i)
+		}
+	}
+	return layout
+}
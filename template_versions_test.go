@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+func TestColumnLayoutTemplateVersions(t *testing.T) {
+	discardLog(t)
+	unknown := []string{"Тип системы", "Стенд", "Что сделать", "Приложение", "Название роли", "Кому", "Доп"}
+
+	if _, err := (RequestTable{Name: "Request", Header: unknown}).columnLayout(); err == nil {
+		t.Error("книга без отметки с нераспознанными заголовками принята")
+	}
+
+	v0 := RequestTable{Name: "Request", Header: unknown[:6], Template: templateStamp{version: 0, marked: true}}
+	layout, err := v0.columnLayout()
+	if err != nil {
+		t.Fatalf("версия 0: %v", err)
+	}
+	if !layout.byPosition {
+		t.Error("версия 0: раскладка по позициям не отмечена")
+	}
+	if _, ok := layout.positions[columnOptions]; ok {
+		t.Error("версия 0: в раскладке есть колонка Options")
+	}
+
+	v1 := RequestTable{Name: "Request", Header: unknown, Template: templateStamp{version: 1, marked: true}}
+	if layout, err = v1.columnLayout(); err != nil {
+		t.Fatalf("версия 1: %v", err)
+	}
+	if pos, ok := layout.positions[columnOptions]; !ok || pos != 6 {
+		t.Errorf("версия 1: колонка Options на позиции %d, ожидалась 6", pos)
+	}
+
+	newer := RequestTable{Name: "Request", Header: unknown, Template: templateStamp{version: templateVersion + 1, marked: true}}
+	if _, err := newer.columnLayout(); err == nil {
+		t.Error("книга шаблона новее программы принята")
+	}
+
+	known := RequestTable{Name: "Request", Header: []string{"Keycloak type", "Keycloak environment", "Action",
+		"Client ID", "Role name", "User logins"}}
+	if layout, err = known.columnLayout(); err != nil || layout.byPosition {
+		t.Errorf("распознанные заголовки: раскладка по позициям %v, ошибка %v", layout != nil && layout.byPosition, err)
+	}
+}
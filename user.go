@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/go-resty/resty/v2"
@@ -16,23 +17,86 @@ const (
 	userSearchTimeout = 5 * time.Second
 )
 
+// Стратегии поиска пользователя по идентификатору из колонки логинов
+const (
+	identifierUsername  = "username"  // точный поиск по логину
+	identifierEmail     = "email"     // точный поиск по e-mail
+	identifierAttribute = "attribute" // поиск по атрибуту через q=attr:value
+	identifierAuto      = "auto"      // выбор стратегии по формату значения
+	identifierAttrAlias = "attr"      // короткий префикс для attribute в ячейке логинов
+)
+
+// identifierOptionPrefix задаёт стратегию для всей строки через колонку Options, например identifier=email
+const identifierOptionPrefix = "identifier="
+
+// Политики обработки отключённых пользователей и пользователей с неподтверждённым e-mail
+const (
+	policySkip  = "skip"  // пользователь пропускается с ошибкой
+	policyWarn  = "warn"  // пользователь обрабатывается с предупреждением
+	policyAllow = "allow" // пользователь обрабатывается без сообщений
+)
+
+// Политики по умолчанию: отключённые учётные записи не получают роли,
+// неподтверждённый e-mail типичен для пользователей из LDAP и не мешает назначению
+const (
+	defaultDisabledPolicy   = policySkip
+	defaultUnverifiedPolicy = policyAllow
+)
+
 // UserResponse представляет структуру ответа API Keycloak при запросе пользователя
 type UserResponse struct {
-	Id string `json:"id"`
+	Id             string `json:"id"`
+	Username       string `json:"username"`
+	Enabled        bool   `json:"enabled"`
+	EmailVerified  bool   `json:"emailVerified"`
+	FederationLink string `json:"federationLink"`
 }
 
-// getUserIdByLdap ищет пользователя в Keycloak по LDAP-логину
+// getUserIdByLdap ищет пользователя в Keycloak по LDAP-логину и применяет политики
+// для отключённых и неподтверждённых пользователей
 func (app *Operation) getUserIdByLdap(ldap string) string {
-	if err := app.checkRateLimit(ldap); err != nil {
+	users := app.lookupUsers(ldap)
+	if len(users) == 0 {
 		return ""
 	}
+	return app.selectUser(ldap, users, true)
+}
 
-	resp, err := app.searchUser(ldap)
-	if err != nil {
+// getAnyUserIdByLdap ищет пользователя без применения политик: для отзыва и просмотра доступов
+// отключённые учётные записи так же важны, как активные
+func (app *Operation) getAnyUserIdByLdap(ldap string) string {
+	users := app.lookupUsers(ldap)
+	if len(users) == 0 {
 		return ""
 	}
+	return app.selectUser(ldap, users, false)
+}
 
-	return app.parseUserResponse(resp, ldap)
+// lookupUsers выполняет поиск по логину; если пользователь не найден, пробует импорт из федерации
+// и сообщает о похожих пользователях
+func (app *Operation) lookupUsers(ldap string) []UserResponse {
+	if err := app.checkRateLimit(ldap); err != nil {
+		return nil
+	}
+
+	strategy, value := app.resolveIdentifier(ldap)
+	params := app.getUserSearchParams(strategy, value)
+	resp, err := app.searchUser(ldap, params)
+	if err != nil {
+		return nil
+	}
+
+	users, err := app.parseUserResponse(resp, ldap)
+	if err != nil {
+		return nil
+	}
+	if len(users) == 0 {
+		users = app.importFromFederation(ldap, params, value)
+	}
+	if len(users) == 0 {
+		app.reportSuggestions(ldap, value)
+	}
+	return users
 }
 
 // checkRateLimit проверяет ограничение частоты запросов
@@ -48,40 +112,215 @@ func (app *Operation) checkRateLimit(ldap string) error {
 }
 
 // searchUser выполняет поиск пользователя в Keycloak
-func (app *Operation) searchUser(ldap string) (*resty.Response, error) {
+func (app *Operation) searchUser(ldap string, params map[string]string) (*resty.Response, error) {
 	resp, err := app.client.R().
 		SetPathParam("instance", app.realm).
-		SetQueryParams(app.getUserSearchParams(ldap)).
+		SetQueryParams(params).
 		Get(usersEndpoint)
 
 	if err != nil || resp.StatusCode() != http.StatusOK {
 		app.AddError(fmt.Sprintf("Ошибка поиска LDAP: %s, статус: %d",
 			ldap, resp.StatusCode()))
+		if err == nil {
+			err = fmt.Errorf("HTTP %d", resp.StatusCode())
+		}
 		return nil, err
 	}
 	return resp, nil
 }
 
-// getUserSearchParams возвращает параметры поиска пользователя
-func (app *Operation) getUserSearchParams(ldap string) map[string]string {
-	return map[string]string{
-		"exact":    "true",
-		"username": ldap,
+// getUserSearchParams возвращает параметры поиска пользователя по выбранной стратегии
+func (app *Operation) getUserSearchParams(strategy, value string) map[string]string {
+	switch strategy {
+	case identifierEmail:
+		return map[string]string{
+			"exact": "true",
+			"email": value,
+		}
+	case identifierAttribute:
+		return map[string]string{
+			"exact": "true",
+			"q":     app.instanceConfig().IdentifierAttribute + ":" + value,
+		}
+	default:
+		return map[string]string{
+			"exact":    "true",
+			"username": value,
+		}
+	}
+}
+
+// resolveIdentifier определяет стратегию поиска и нормализованное значение идентификатора
+func (app *Operation) resolveIdentifier(ldap string) (string, string) {
+	strategy, value, notes := app.identifierStrategy(ldap)
+	for _, note := range notes {
+		app.AddError(note)
+	}
+	return strategy, app.normalizeIdentifier(ldap, strategy, value)
+}
+
+// identifierStrategy определяет стратегию поиска и значение идентификатора без нормализации и сообщений.
+// Приоритет: префикс в ячейке (email:, attr:, username:), опция identifier= в Options, настройка инстанса.
+// Замечания о неизвестных стратегиях и ненастроенном атрибуте возвращаются для вывода вызывающим
+func (app *Operation) identifierStrategy(ldap string) (string, string, []string) {
+	var notes []string
+	strategy, value := splitIdentifierPrefix(ldap)
+	if strategy == "" {
+		var unknown []string
+		strategy, unknown = app.identifierOption()
+		for _, option := range unknown {
+			notes = append(notes, fmt.Sprintf("Неизвестная стратегия поиска пользователя в Options: %s", option))
+		}
+	}
+	if strategy == "" {
+		strategy = app.instanceConfig().Identifier
+	}
+	switch strategy {
+	case "":
+		strategy = identifierUsername
+	case identifierAuto:
+		strategy = app.detectIdentifier(value)
+	}
+	if strategy == identifierAttribute && app.instanceConfig().IdentifierAttribute == "" {
+		notes = append(notes, fmt.Sprintf("Для %s не настроен identifier_attribute инстанса %s, используется поиск по логину",
+			ldap, app.instance))
+		strategy = identifierUsername
+	}
+	return strategy, value, notes
+}
+
+// splitIdentifierPrefix отделяет префикс стратегии от значения, например "email:ivanov@corp.ru"
+func splitIdentifierPrefix(ldap string) (string, string) {
+	prefix, value, found := strings.Cut(ldap, ":")
+	if !found {
+		return "", ldap
+	}
+
+	switch strings.ToLower(strings.TrimSpace(prefix)) {
+	case identifierUsername:
+		return identifierUsername, strings.TrimSpace(value)
+	case identifierEmail:
+		return identifierEmail, strings.TrimSpace(value)
+	case identifierAttribute, identifierAttrAlias:
+		return identifierAttribute, strings.TrimSpace(value)
+	default:
+		return "", ldap
+	}
+}
+
+// identifierOption возвращает стратегию, заданную опцией identifier= в колонке Options,
+// и неизвестные стратегии, указанные до неё
+func (app *Operation) identifierOption() (string, []string) {
+	var unknown []string
+	for _, option := range app.options {
+		if strategy, found := strings.CutPrefix(option, identifierOptionPrefix); found {
+			if isIdentifierStrategy(strategy) {
+				return strategy, unknown
+			}
+			unknown = append(unknown, strategy)
+		}
+	}
+	return "", unknown
+}
+
+// detectIdentifier определяет стратегию по формату значения: e-mail, табельный номер или логин
+func (app *Operation) detectIdentifier(value string) string {
+	if strings.Contains(value, "@") {
+		return identifierEmail
+	}
+	if app.instanceConfig().IdentifierAttribute != "" && isDigits(value) {
+		return identifierAttribute
 	}
+	return identifierUsername
+}
+
+// instanceConfig возвращает настройки инстанса операции
+func (app *Operation) instanceConfig() InstanceConfig {
+	return config.instance(app.instance, app.environment)
+}
+
+// isIdentifierStrategy проверяет название стратегии поиска пользователя
+func isIdentifierStrategy(strategy string) bool {
+	switch strategy {
+	case identifierUsername, identifierEmail, identifierAttribute, identifierAuto:
+		return true
+	}
+	return false
+}
+
+// isDigits проверяет, что строка состоит только из цифр
+func isDigits(value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
 }
 
 // parseUserResponse обрабатывает ответ от Keycloak
-func (app *Operation) parseUserResponse(resp *resty.Response, ldap string) string {
+func (app *Operation) parseUserResponse(resp *resty.Response, ldap string) ([]UserResponse, error) {
 	var users []UserResponse
 	if err := json.Unmarshal(resp.Body(), &users); err != nil {
 		app.AddError(fmt.Sprintf("Ошибка парсинга пользователя: %s", ldap))
-		return ""
+		return nil, err
 	}
+	return users, nil
+}
 
-	if len(users) == 0 {
-		app.AddError(fmt.Sprintf("LDAP не найден: %s", ldap))
+// selectUser отклоняет неоднозначные совпадения и, если applyPolicies, применяет политики
+// для отключённых и неподтверждённых пользователей. Возвращает ID пользователя или пустую строку
+func (app *Operation) selectUser(ldap string, users []UserResponse, applyPolicies bool) string {
+	if len(users) > 1 {
+		usernames := make([]string, 0, len(users))
+		for _, user := range users {
+			usernames = append(usernames, user.Username)
+		}
+		app.AddError(fmt.Sprintf("Логин %s неоднозначен, найдено %d пользователей: %s. Пропуск",
+			ldap, len(users), strings.Join(usernames, ", ")))
+		app.addOutcome(ldap, "неоднозначно", strings.Join(usernames, ", "))
 		return ""
 	}
 
-	return users[0].Id
+	user := users[0]
+	source := "local"
+	if user.FederationLink != "" {
+		source = "federation " + user.FederationLink
+	}
+
+	if applyPolicies {
+		cfg := app.instanceConfig()
+		if !user.Enabled && !app.applyUserPolicy(ldap, user, cfg.disabledPolicy(), "учётная запись отключена", source) {
+			return ""
+		}
+		if !user.EmailVerified && !app.applyUserPolicy(ldap, user, cfg.unverifiedPolicy(), "e-mail не подтверждён", source) {
+			return ""
+		}
+	}
+	logInfo("%s: найден пользователь %s (%s)", ldap, user.Username, source)
+	return user.Id
+}
+
+// applyUserPolicy применяет политику к пользователю с нарушением и сообщает, можно ли его обрабатывать
+func (app *Operation) applyUserPolicy(ldap string, user UserResponse, policy, problem, source string) bool {
+	switch policy {
+	case policyAllow:
+		return true
+	case policyWarn:
+		logWarn("%s (%s, %s): %s, обработка продолжена", ldap, user.Username, source, problem)
+		app.addOutcome(ldap, "предупреждение", problem)
+		return true
+	default:
+		app.AddError(fmt.Sprintf("%s (%s, %s): %s. Пропуск", ldap, user.Username, source, problem))
+		app.addOutcome(ldap, "пропущен", problem)
+		return false
+	}
+}
+
+// isUserPolicy проверяет название политики обработки пользователей
+func isUserPolicy(policy string) bool {
+	return policy == policySkip || policy == policyWarn || policy == policyAllow
 }
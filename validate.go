@@ -0,0 +1,288 @@
+// validate.go реализует команду validate
+//   - Проверяет каждую строку файлов запросов на живом инстансе только чтением
+//   - Доступность инстанса и учётные данные, клиент, группа Roles, роль, логины
+//   - Выводит вердикт по каждой строке до применения изменений
+//   - Проверяет конфликты между строками всех файлов так же, как перед обработкой
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Вердикты проверки строки
+const (
+	verdictOK    = "OK"
+	verdictWarn  = "WARN"
+	verdictError = "ERROR"
+)
+
+// rowCheck содержит результат проверки одной строки
+type rowCheck struct {
+	verdict string
+	details []string
+}
+
+// fail отмечает строку как ошибочную
+func (c *rowCheck) fail(format string, v ...interface{}) {
+	c.verdict = verdictError
+	c.details = append(c.details, fmt.Sprintf(format, v...))
+}
+
+// warn добавляет предупреждение, не понижая ошибочный вердикт
+func (c *rowCheck) warn(format string, v ...interface{}) {
+	if c.verdict == verdictOK {
+		c.verdict = verdictWarn
+	}
+	c.details = append(c.details, fmt.Sprintf(format, v...))
+}
+
+// runValidate выполняет команду validate
+func (a *App) runValidate(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	var report reportFlags
+	report.register(fs, formatTable)
+	inputFormat := fs.String("input-format", "", "формат файлов запросов: xlsx, xls, ods, csv, yaml, json (по умолчанию - по расширению)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Использование: validate [параметры] [файл1.xlsx ...]")
+		fmt.Fprintln(fs.Output(), "Без файлов проверяются файлы запросов рядом с исполняемым файлом.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := validateReportFormat(report.format); err != nil {
+		return err
+	}
+
+	files := fs.Args()
+	if len(files) == 0 {
+		exeDir, err := executableDir()
+		if err != nil {
+			return err
+		}
+		if files, err = findRequestFiles(exeDir, *inputFormat); err != nil {
+			return err
+		}
+	}
+
+	result := Report{Headers: []string{"File", "Row", "Instance", "Environment", "Action", "Client", "Role", "Verdict", "Details"}}
+	failed := 0
+	var batches []requestBatch
+	for _, file := range files {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		fileFailed, operations := validateFile(file, *inputFormat, &result)
+		failed += fileFailed
+		batches = append(batches, requestBatch{file: file, operations: operations})
+	}
+	if _, err := analyzeBatches(batches); err != nil {
+		result.AddRow("*", "", "", "", "", "", "", verdictError, err.Error())
+		failed++
+	}
+
+	if report.format == formatExcel && report.output == "" {
+		var err error
+		if report.output, err = defaultReportPath("validate", report.format); err != nil {
+			return err
+		}
+	}
+	if err := result.Write(report.format, report.output); err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("проверка не пройдена: строк с ошибками %d", failed)
+	}
+	logInfo("Проверка пройдена: строк %d", len(result.Rows))
+	return nil
+}
+
+// validateFile проверяет все строки файла и возвращает число строк с ошибками и операции строк для проверки конфликтов
+func validateFile(file, format string, report *Report) (int, []Operation) {
+	name := filepath.Base(file)
+	logInfo("Проверка файла: %s", name)
+
+	source, ok := requestSources[requestFormat(file, format)]
+	if !ok {
+		report.AddRow(name, "", "", "", "", "", "", verdictError, "неподдерживаемый формат файла")
+		return 1, nil
+	}
+	tables, err := source.Read(file)
+	if err != nil {
+		report.AddRow(name, "", "", "", "", "", "", verdictError, err.Error())
+		return 1, nil
+	}
+
+	failed := 0
+	var operations []Operation
+	for _, table := range tables {
+		layout, err := table.columnLayout()
+		if err != nil {
+			report.AddRow(name, "", "", "", "", "", "", verdictError, table.Name+": "+err.Error())
+			failed++
+			continue
+		}
+		if layout.byPosition {
+			report.AddRow(name, "", "", "", "", "", "", verdictError,
+				table.Name+": заголовки не распознаны, колонки взяты по позициям шаблона. Исправьте заголовки")
+			failed++
+		}
+		for i, row := range table.Rows {
+			rowNum := i + table.FirstRow
+			mapped, _ := layout.mapRow(row)
+			mapped, err := table.resolveLogins(mapped, rowNum)
+			if err != nil {
+				mapped = padRow(mapped, maxColumnsCount)
+				report.AddRow(tableSource(file, table), strconv.Itoa(rowNum), mapped[0], mapped[1], mapped[2],
+					mapped[3], mapped[4], verdictError, err.Error())
+				failed++
+				continue
+			}
+			rowOperations, passed := validateRow(tableSource(file, table), mapped, rowNum, report)
+			if !passed {
+				failed++
+			}
+			operations = append(operations, rowOperations...)
+		}
+	}
+	return failed, operations
+}
+
+// validateRow проверяет одну строку, возвращает её операции и сообщает, прошла ли она без ошибок
+func validateRow(name string, row []string, rowNum int, report *Report) ([]Operation, bool) {
+	operations, err := createOperationsFromRow(row, rowNum)
+	if err != nil {
+		row = padRow(row, maxColumnsCount)
+		report.AddRow(name, strconv.Itoa(rowNum), row[0], row[1], row[2], row[3], row[4], verdictError,
+			strings.TrimPrefix(err.Error(), "WARN - "))
+		return nil, false
+	}
+
+	passed := true
+	for i := range operations {
+		operations[i].sourceFile = name
+	}
+	for _, operation := range operations {
+		check := operation.validateLive()
+		if operation.bundle != "" {
+			check.details = append([]string{"набор " + operation.bundle}, check.details...)
+		}
+		report.AddRow(name, strconv.Itoa(rowNum), operation.instance, operation.environment, operation.action,
+			operation.ClientIdName, operation.roleName, check.verdict, strings.Join(check.details, "; "))
+		if check.verdict == verdictError {
+			passed = false
+		}
+	}
+	return operations, passed
+}
+
+// validateLive проверяет операцию на живом инстансе, не внося изменений.
+// Сообщения, накопленные операцией при проверке, добавляются в подробности вердикта
+func (app *Operation) validateLive() (check rowCheck) {
+	app.readOnly = true
+	check = rowCheck{verdict: verdictOK}
+	defer func() {
+		for i := 1; i <= app.errorCounter; i++ {
+			check.details = append(check.details, app.errors[i])
+		}
+	}()
+
+	if err := app.Authenticate(); err != nil {
+		check.fail("инстанс недоступен или неверные учётные данные: %v", err)
+		return check
+	}
+	if err := app.FindClientIdByName(); err != nil {
+		check.fail("клиент %s не найден или не уникален", app.ClientIdName)
+		return check
+	}
+	app.resolveTemplatedNames()
+
+	rolesGroup, err := app.findRolesGroup()
+	if err != nil {
+		check.fail("группа %s не найдена", rolesGroupName)
+		return check
+	}
+	app.parentGroupId = app.findClientSubgroup(rolesGroup)
+
+	app.validateRoles(&check)
+	app.validateLogins(&check)
+	return check
+}
+
+// validateRoles проверяет наличие ролей и подгрупп, которые нужны действию
+func (app *Operation) validateRoles(check *rowCheck) {
+	roleExists := app.findRole(app.roleName, false) != ""
+	subGroupExists := app.parentGroupId != "" && app.getSubGroupByName(app.roleName) != ""
+
+	switch app.action {
+	case actionCreate:
+		if roleExists || subGroupExists {
+			check.warn("роль %s уже существует, будет выполнено '%s'", app.roleName, actionAssociate)
+		}
+		if app.parentGroupId == "" {
+			check.warn("подгруппа клиента %s/%s будет создана", rolesGroupName, app.ClientIdName)
+		}
+	case actionAssociate, actionRemove:
+		if !roleExists || !subGroupExists {
+			check.fail("роль %s или её подгруппа не существует", app.roleName)
+		}
+	case actionDelete:
+		if !roleExists && !subGroupExists {
+			check.fail("роль %s не существует, удалять нечего", app.roleName)
+		}
+	case actionRename:
+		if !roleExists {
+			check.fail("роль %s не существует", app.roleName)
+		}
+		if app.findRole(app.targetRoleName, false) != "" ||
+			(app.parentGroupId != "" && app.getSubGroupByName(app.targetRoleName) != "") {
+			check.fail("роль или подгруппа %s уже существует", app.targetRoleName)
+		}
+	case actionCopyMembers, actionMoveMembers:
+		if app.roleName == app.targetRoleName {
+			check.fail("исходная и целевая роль совпадают: %s", app.roleName)
+			return
+		}
+		if !subGroupExists {
+			check.fail("подгруппа исходной роли %s не существует", app.roleName)
+		}
+		targetExists := app.findRole(app.targetRoleName, false) != "" &&
+			app.parentGroupId != "" && app.getSubGroupByName(app.targetRoleName) != ""
+		if !targetExists && !app.hasOption(optionCreateTarget) {
+			check.fail("целевая роль %s не существует, нужна опция %s", app.targetRoleName, optionCreateTarget)
+		} else if !targetExists {
+			check.warn("целевая роль %s будет создана", app.targetRoleName)
+		}
+	}
+}
+
+// validateLogins проверяет, что каждый логин находится в Keycloak
+func (app *Operation) validateLogins(check *rowCheck) {
+	if len(app.ldaps) == 1 && app.ldaps[0] == allMembersMarker {
+		return
+	}
+
+	errorsBefore := app.errorCounter
+	var missing []string
+	lookup := app.getUserIdByLdap
+	if app.action == actionRemove || app.action == actionCopyMembers || app.action == actionMoveMembers {
+		// Убрать доступ можно и у отключённого пользователя, политики здесь не применяются
+		lookup = app.getAnyUserIdByLdap
+	}
+	for _, ldap := range app.ldaps {
+		if lookup(ldap) == "" {
+			missing = append(missing, ldap)
+		}
+	}
+	if len(missing) > 0 {
+		check.fail("не найдены или не проходят политики логины: %s", strings.Join(missing, ", "))
+	} else if app.errorCounter > errorsBefore {
+		check.warn("есть замечания по логинам")
+	}
+}
@@ -0,0 +1,233 @@
+// whois.go реализует команду whois
+//   - Собирает доступы пользователя во всех инстансах и окружениях
+//   - Группы в дереве Roles, эффективные клиентские и realm-роли
+//   - Статус учётной записи и связь с федерацией пользователей
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+)
+
+const (
+	userEndpoint              = "/admin/realms/{instance}/users/{userId}"
+	userRealmRolesEndpoint    = "/admin/realms/{instance}/users/{userId}/role-mappings/realm/composite"
+	userClientRolesEndpoint   = "/admin/realms/{instance}/users/{userId}/role-mappings/clients/{clientId}/composite"
+	groupRoleMappingsEndpoint = "/admin/realms/{instance}/groups/{groupId}/role-mappings"
+)
+
+// Виды доступа в отчёте whois
+const (
+	accessStatus     = "status"
+	accessGroup      = "group"
+	accessClientRole = "client-role"
+	accessRealmRole  = "realm-role"
+)
+
+// UserDetails содержит сведения об учётной записи пользователя
+type UserDetails struct {
+	ID             string `json:"id"`             // Внутренний UUID пользователя
+	Username       string `json:"username"`       // Логин
+	Email          string `json:"email"`          // Адрес электронной почты
+	FirstName      string `json:"firstName"`      // Имя
+	LastName       string `json:"lastName"`       // Фамилия
+	Enabled        bool   `json:"enabled"`        // Включена ли учётная запись
+	FederationLink string `json:"federationLink"` // ID провайдера федерации, из которого импортирован пользователь
+}
+
+// runWhois выполняет команду whois
+func (a *App) runWhois(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("whois", flag.ContinueOnError)
+	var target targetFlags
+	var report reportFlags
+	target.register(fs)
+	report.register(fs, formatTable)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Использование: whois [параметры] login1 [login2 ...]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := validateReportFormat(report.format); err != nil {
+		return err
+	}
+
+	logins := parseLDAPs(strings.Join(fs.Args(), ","))
+	if len(logins) == 0 {
+		fs.Usage()
+		return fmt.Errorf("не указаны логины")
+	}
+
+	operations, err := target.targets()
+	if err != nil {
+		return err
+	}
+
+	result := Report{Headers: []string{"Login", "Instance", "Environment", "Enabled", "Federation", "Kind", "Access"}}
+	for i := range operations {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		operations[i].collectUserAccess(logins, &result)
+		operations[i].printErrors()
+	}
+
+	if report.format == formatExcel && report.output == "" {
+		if report.output, err = defaultReportPath("whois", report.format); err != nil {
+			return err
+		}
+	}
+	if err := result.Write(report.format, report.output); err != nil {
+		return err
+	}
+	if report.output != "" {
+		logInfo("Отчёт о доступах сохранён в %s", report.output)
+	}
+	return nil
+}
+
+// collectUserAccess добавляет в отчёт доступы пользователей в одном инстансе.
+// Для каждого логина всегда выводится строка статуса: учётная запись найдена или текст ошибки поиска
+func (app *Operation) collectUserAccess(logins []string, report *Report) {
+	logInfo("Сбор доступов: %s %s", app.instance, app.environment)
+	if err := app.Authenticate(); err != nil {
+		for _, login := range logins {
+			report.AddRow(login, app.instance, app.environment, "", "", accessStatus, "ошибка аутентификации: "+err.Error())
+		}
+		return
+	}
+	clients, clientsErr := app.listRealmClients()
+
+	for _, login := range logins {
+		userId := app.getAnyUserIdByLdap(login)
+		if userId == "" {
+			report.AddRow(login, app.instance, app.environment, "", "", accessStatus, "пользователь не найден")
+			continue
+		}
+
+		var user UserDetails
+		if err := app.getJSON(userEndpoint, map[string]string{"userId": userId}, &user); err != nil {
+			report.AddRow(login, app.instance, app.environment, "", "", accessStatus,
+				"ошибка получения учётной записи: "+err.Error())
+			continue
+		}
+
+		federation := user.FederationLink
+		if federation == "" {
+			federation = "local"
+		}
+		var access [][2]string
+		var failures []string
+
+		groups, err := app.getUserRoleGroups(userId)
+		if err != nil {
+			failures = append(failures, "ошибка получения групп: "+err.Error())
+		}
+		for _, group := range groups {
+			access = append(access, [2]string{accessGroup, group.Path})
+		}
+
+		if clientsErr != nil {
+			failures = append(failures, "ошибка получения списка клиентов: "+clientsErr.Error())
+		}
+		clientRoles, err := app.getEffectiveClientRoles(userId, clients)
+		if err != nil {
+			failures = append(failures, "ошибка получения клиентских ролей: "+err.Error())
+		}
+		for _, role := range clientRoles {
+			access = append(access, [2]string{accessClientRole, role})
+		}
+
+		var realmRoles []Role
+		if err := app.getJSON(userRealmRolesEndpoint, map[string]string{"userId": userId}, &realmRoles); err != nil {
+			failures = append(failures, "ошибка получения realm-ролей: "+err.Error())
+		}
+		for _, role := range realmRoles {
+			access = append(access, [2]string{accessRealmRole, role.Name})
+		}
+
+		status := "найден"
+		if len(failures) > 0 {
+			status = strings.Join(failures, "; ")
+		}
+		enabled := strconv.FormatBool(user.Enabled)
+		report.AddRow(login, app.instance, app.environment, enabled, federation, accessStatus, status)
+		for _, row := range access {
+			report.AddRow(login, app.instance, app.environment, enabled, federation, row[0], row[1])
+		}
+	}
+}
+
+// listRealmClients возвращает все клиенты realm для поиска эффективных клиентских ролей
+func (app *Operation) listRealmClients() ([]Client, error) {
+	clients, err := fetchAllPages[Client](func(first, max int) (*resty.Response, error) {
+		return app.client.R().
+			SetPathParam("instance", app.realm).
+			SetQueryParams(map[string]string{
+				"first": strconv.Itoa(first),
+				"max":   strconv.Itoa(max),
+			}).
+			Get(clientsEndpoint)
+	})
+	if err != nil {
+		app.AddError(fmt.Sprintf("Ошибка получения списка клиентов: %v", err))
+		return nil, err
+	}
+	return clients, nil
+}
+
+// getEffectiveClientRoles возвращает эффективные клиентские роли пользователя в виде "клиент/роль".
+// Проверяется каждый клиент realm: так учитываются роли из любых групп и их родителей,
+// составных realm-ролей и ролей по умолчанию
+func (app *Operation) getEffectiveClientRoles(userId string, clients []Client) ([]string, error) {
+	var roles []string
+	var failed []string
+	for _, client := range clients {
+		var effective []Role
+		params := map[string]string{"userId": userId, "clientId": client.ID}
+		if err := app.getJSON(userClientRolesEndpoint, params, &effective); err != nil {
+			failed = append(failed, client.ClientID)
+			continue
+		}
+		for _, role := range effective {
+			roles = append(roles, client.ClientID+"/"+role.Name)
+		}
+	}
+
+	sort.Strings(roles)
+	if len(failed) > 0 {
+		return roles, fmt.Errorf("клиенты %s", strings.Join(failed, ", "))
+	}
+	return roles, nil
+}
+
+// getJSON запрашивает ресурс Keycloak и разбирает ответ в указанную структуру
+func (app *Operation) getJSON(endpoint string, params map[string]string, v interface{}) error {
+	res, err := app.client.R().
+		SetPathParam("instance", app.realm).
+		SetPathParams(params).
+		Get(endpoint)
+
+	if err != nil {
+		app.AddError(fmt.Sprintf("Ошибка запроса %s (%v): %v", endpoint, params, err))
+		return err
+	}
+	if res.StatusCode() != http.StatusOK {
+		app.AddError(fmt.Sprintf("Ошибка запроса %s (%v): статус %d", endpoint, params, res.StatusCode()))
+		return fmt.Errorf("HTTP %d", res.StatusCode())
+	}
+	if err := json.Unmarshal(res.Body(), v); err != nil {
+		app.AddError(fmt.Sprintf("Ошибка парсинга ответа %s: %v", endpoint, err))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,509 @@
+// xls_source.go читает книги Excel 97-2003 (.xls, формат BIFF8)
+//   - Книга хранится в контейнере OLE2, листы лежат в потоке Workbook
+//   - Поддерживаются текстовые ячейки (общая таблица строк и LABEL), числа и результаты формул
+//   - Файлы .xls, которые на самом деле являются .xlsx (в том числе зашифрованными), читаются через excelize
+//   - HTML и Excel 5.0/95, сохранённые с расширением .xls, распознаются с понятной ошибкой
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"unicode/utf16"
+
+	"github.com/richardlehane/mscfb"
+)
+
+// Типы записей BIFF8, которые нужны для чтения значений ячеек
+const (
+	biffBOF        = 0x0809
+	biffEOF        = 0x000A
+	biffFilePass   = 0x002F
+	biffBoundSheet = 0x0085
+	biffSST        = 0x00FC
+	biffContinue   = 0x003C
+	biffLabelSST   = 0x00FD
+	biffLabel      = 0x0204
+	biffNumber     = 0x0203
+	biffRK         = 0x027E
+	biffMulRK      = 0x00BD
+	biffFormula    = 0x0006
+	biffString     = 0x0207
+	biffBoolErr    = 0x0205
+	biff8Version   = 0x0600
+)
+
+// Сигнатуры контейнеров, по которым определяется настоящий формат файла .xls
+var (
+	oleSignature = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+	zipSignature = []byte("PK\x03\x04")
+)
+
+// xlsSource читает листы запросов книги Excel 97-2003
+type xlsSource struct{}
+
+// Read определяет настоящий формат файла .xls и читает листы запросов
+func (xlsSource) Read(path string) ([]RequestTable, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка открытия файла: %w", err)
+	}
+
+	switch {
+	case bytes.HasPrefix(data, zipSignature), isEncryptedPackage(data):
+		return excelSource{}.Read(path)
+	case bytes.HasPrefix(data, oleSignature):
+		sheets, err := readBIFFWorkbook(data)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения книги Excel 97-2003: %w", err)
+		}
+		return requestSheetTables(newExcelConfig(path), sheets)
+	default:
+		return nil, fmt.Errorf("файл %s не является книгой Excel: вероятно, это HTML или текст, сохранённый "+
+			"с расширением .xls. Откройте его в Excel и сохраните как .xlsx", filepath.Base(path))
+	}
+}
+
+// readBIFFWorkbook извлекает поток Workbook из контейнера OLE2 и читает все рабочие листы
+func readBIFFWorkbook(data []byte) ([]workbookSheet, error) {
+	doc, err := mscfb.New(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	for entry, err := doc.Next(); err == nil; entry, err = doc.Next() {
+		switch entry.Name {
+		case "Workbook":
+			stream, err := io.ReadAll(entry)
+			if err != nil {
+				return nil, err
+			}
+			return parseBIFFWorkbook(stream)
+		case "Book":
+			return nil, errors.New("формат Excel 5.0/95 не поддерживается, сохраните книгу как .xlsx")
+		}
+	}
+	return nil, errors.New("в файле нет потока Workbook")
+}
+
+// isEncryptedPackage проверяет, что файл - зашифрованная паролем книга .xlsx:
+// такая книга хранится в контейнере OLE2 в потоке EncryptedPackage
+func isEncryptedPackage(data []byte) bool {
+	if !bytes.HasPrefix(data, oleSignature) {
+		return false
+	}
+	doc, err := mscfb.New(bytes.NewReader(data))
+	if err != nil {
+		return false
+	}
+	for entry, err := doc.Next(); err == nil; entry, err = doc.Next() {
+		if entry.Name == "EncryptedPackage" {
+			return true
+		}
+	}
+	return false
+}
+
+// isEncryptedWorkbook проверяет, что файл книги зашифрован паролем
+func isEncryptedWorkbook(path string) bool {
+	data, err := os.ReadFile(path)
+	return err == nil && isEncryptedPackage(data)
+}
+
+// biffRecord содержит тип и данные записи BIFF
+type biffRecord struct {
+	kind uint16
+	data []byte
+}
+
+// readBIFFRecord читает запись, начинающуюся с позиции pos, и возвращает позицию следующей
+func readBIFFRecord(stream []byte, pos int) (biffRecord, int, bool) {
+	if pos < 0 || pos+4 > len(stream) {
+		return biffRecord{}, pos, false
+	}
+	kind := binary.LittleEndian.Uint16(stream[pos:])
+	size := int(binary.LittleEndian.Uint16(stream[pos+2:]))
+	end := pos + 4 + size
+	if end > len(stream) {
+		return biffRecord{}, pos, false
+	}
+	return biffRecord{kind: kind, data: stream[pos+4 : end]}, end, true
+}
+
+// biffSheetRef содержит имя рабочего листа и смещение его записей в потоке
+type biffSheetRef struct {
+	name   string
+	offset int
+}
+
+// parseBIFFWorkbook читает глобальные записи книги (листы, общая таблица строк) и затем каждый лист
+func parseBIFFWorkbook(stream []byte) ([]workbookSheet, error) {
+	record, pos, ok := readBIFFRecord(stream, 0)
+	if !ok || record.kind != biffBOF || len(record.data) < 2 {
+		return nil, errors.New("поток Workbook повреждён")
+	}
+	if binary.LittleEndian.Uint16(record.data) != biff8Version {
+		return nil, errors.New("поддерживается только формат Excel 97-2003 (BIFF8), сохраните книгу как .xlsx")
+	}
+
+	var refs []biffSheetRef
+	var sst []string
+	for {
+		record, next, ok := readBIFFRecord(stream, pos)
+		if !ok || record.kind == biffEOF {
+			break
+		}
+
+		switch record.kind {
+		case biffFilePass:
+			return nil, errors.New("книга защищена паролем")
+		case biffBoundSheet:
+			if ref, ok := parseBoundSheet(record.data); ok {
+				refs = append(refs, ref)
+			}
+		case biffSST:
+			segments := [][]byte{record.data}
+			for {
+				cont, after, ok := readBIFFRecord(stream, next)
+				if !ok || cont.kind != biffContinue {
+					break
+				}
+				segments = append(segments, cont.data)
+				next = after
+			}
+			var err error
+			if sst, err = parseSST(segments); err != nil {
+				return nil, fmt.Errorf("ошибка чтения таблицы строк: %w", err)
+			}
+		}
+		pos = next
+	}
+
+	sheets := make([]workbookSheet, 0, len(refs))
+	for _, ref := range refs {
+		sheets = append(sheets, workbookSheet{Name: ref.name, Rows: parseBIFFSheet(stream, ref.offset, sst)})
+	}
+	return sheets, nil
+}
+
+// parseBoundSheet разбирает запись BOUNDSHEET; диаграммы и макросы пропускаются
+func parseBoundSheet(data []byte) (biffSheetRef, bool) {
+	if len(data) < 8 || data[5] != 0 {
+		return biffSheetRef{}, false
+	}
+	name, ok := decodeBIFFChars(data[8:], int(data[6]), data[7]&1 != 0)
+	if !ok {
+		return biffSheetRef{}, false
+	}
+	return biffSheetRef{name: name, offset: int(binary.LittleEndian.Uint32(data))}, true
+}
+
+// parseBIFFSheet читает значения ячеек листа от записи BOF до парной ей записи EOF.
+// Вложенные потоки (встроенные диаграммы) со своими BOF/EOF пропускаются
+func parseBIFFSheet(stream []byte, offset int, sst []string) [][]string {
+	var rows [][]string
+	record, pos, ok := readBIFFRecord(stream, offset)
+	if !ok || record.kind != biffBOF {
+		return nil
+	}
+
+	pendingRow, pendingCol := -1, -1
+	depth := 0
+	for {
+		record, next, ok := readBIFFRecord(stream, pos)
+		if !ok {
+			return rows
+		}
+		pos = next
+
+		switch {
+		case record.kind == biffBOF:
+			depth++
+			continue
+		case record.kind == biffEOF && depth == 0:
+			return rows
+		case record.kind == biffEOF:
+			depth--
+			continue
+		case depth > 0:
+			continue
+		}
+
+		data := record.data
+		if len(data) < 6 && record.kind != biffString {
+			continue
+		}
+
+		switch record.kind {
+		case biffLabelSST:
+			if len(data) >= 10 {
+				if index := int(binary.LittleEndian.Uint32(data[6:])); index < len(sst) {
+					rows = setBIFFCell(rows, data, sst[index])
+				}
+			}
+		case biffLabel:
+			if len(data) >= 9 {
+				if value, ok := decodeBIFFChars(data[9:], int(binary.LittleEndian.Uint16(data[6:])), data[8]&1 != 0); ok {
+					rows = setBIFFCell(rows, data, value)
+				}
+			}
+		case biffNumber:
+			if len(data) >= 14 {
+				rows = setBIFFCell(rows, data, formatBIFFNumber(math.Float64frombits(binary.LittleEndian.Uint64(data[6:]))))
+			}
+		case biffRK:
+			if len(data) >= 10 {
+				rows = setBIFFCell(rows, data, formatBIFFNumber(decodeRK(binary.LittleEndian.Uint32(data[6:]))))
+			}
+		case biffMulRK:
+			row := int(binary.LittleEndian.Uint16(data))
+			col := int(binary.LittleEndian.Uint16(data[2:]))
+			for i := 4; i+6 <= len(data)-2; i += 6 {
+				rows = setSheetCell(rows, row, col, formatBIFFNumber(decodeRK(binary.LittleEndian.Uint32(data[i+2:]))))
+				col++
+			}
+		case biffBoolErr:
+			if len(data) >= 8 && data[7] == 0 {
+				rows = setBIFFCell(rows, data, formatBIFFBool(data[6]))
+			}
+		case biffFormula:
+			if len(data) < 14 {
+				continue
+			}
+			result := data[6:14]
+			if binary.LittleEndian.Uint16(result[6:]) != 0xFFFF {
+				rows = setBIFFCell(rows, data, formatBIFFNumber(math.Float64frombits(binary.LittleEndian.Uint64(result))))
+				continue
+			}
+			switch result[0] {
+			case 0:
+				// Строковый результат формулы хранится в следующей записи STRING
+				pendingRow = int(binary.LittleEndian.Uint16(data))
+				pendingCol = int(binary.LittleEndian.Uint16(data[2:]))
+			case 1:
+				rows = setBIFFCell(rows, data, formatBIFFBool(result[2]))
+			}
+		case biffString:
+			if pendingRow >= 0 && len(data) >= 3 {
+				if value, ok := decodeBIFFChars(data[3:], int(binary.LittleEndian.Uint16(data)), data[2]&1 != 0); ok {
+					rows = setSheetCell(rows, pendingRow, pendingCol, value)
+				}
+			}
+			pendingRow, pendingCol = -1, -1
+		}
+	}
+}
+
+// setBIFFCell записывает значение ячейки, координаты которой находятся в начале записи
+func setBIFFCell(rows [][]string, data []byte, value string) [][]string {
+	row := int(binary.LittleEndian.Uint16(data))
+	col := int(binary.LittleEndian.Uint16(data[2:]))
+	return setSheetCell(rows, row, col, value)
+}
+
+// decodeRK декодирует компактное числовое значение RK
+func decodeRK(rk uint32) float64 {
+	var value float64
+	if rk&0x02 != 0 {
+		value = float64(int32(rk) >> 2)
+	} else {
+		value = math.Float64frombits(uint64(rk&0xFFFFFFFC) << 32)
+	}
+	if rk&0x01 != 0 {
+		value /= 100
+	}
+	return value
+}
+
+// formatBIFFNumber форматирует число без лишних нулей, как его показывает Excel в общем формате
+func formatBIFFNumber(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// formatBIFFBool форматирует логическое значение так же, как excelize
+func formatBIFFBool(value byte) string {
+	if value != 0 {
+		return "TRUE"
+	}
+	return "FALSE"
+}
+
+// decodeBIFFChars декодирует count символов: однобайтовых (Latin-1) или UTF-16LE
+func decodeBIFFChars(data []byte, count int, wide bool) (string, bool) {
+	if !wide {
+		if len(data) < count {
+			return "", false
+		}
+		runes := make([]rune, count)
+		for i := 0; i < count; i++ {
+			runes[i] = rune(data[i])
+		}
+		return string(runes), true
+	}
+
+	if len(data) < count*2 {
+		return "", false
+	}
+	units := make([]uint16, count)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(data[i*2:])
+	}
+	return string(utf16.Decode(units)), true
+}
+
+// sstReader читает общую таблицу строк, разбитую на записи SST и CONTINUE.
+// Символы строки, перенесённые в CONTINUE, начинаются с нового байта флагов
+type sstReader struct {
+	segments [][]byte
+	segment  int
+	pos      int
+}
+
+// errSSTTruncated сообщает о том, что таблица строк закончилась раньше ожидаемого
+var errSSTTruncated = errors.New("таблица строк обрезана")
+
+// remaining возвращает число непрочитанных байт во всех записях
+func (r *sstReader) remaining() int {
+	total := 0
+	for i := r.segment; i < len(r.segments); i++ {
+		total += len(r.segments[i])
+	}
+	return total - r.pos
+}
+
+// skip пропускает n байт, не выделяя под них память: размер берётся из файла и может быть повреждён
+func (r *sstReader) skip(n int) error {
+	if n > r.remaining() {
+		return errSSTTruncated
+	}
+	for n > 0 {
+		current := r.segments[r.segment]
+		if r.pos >= len(current) {
+			r.segment++
+			r.pos = 0
+			continue
+		}
+		take := min(n, len(current)-r.pos)
+		r.pos += take
+		n -= take
+	}
+	return nil
+}
+
+// read читает n байт служебных данных, переходя между записями без байта флагов
+func (r *sstReader) read(n int) ([]byte, error) {
+	if n > r.remaining() {
+		return nil, errSSTTruncated
+	}
+	out := make([]byte, 0, n)
+	for len(out) < n {
+		if r.segment >= len(r.segments) {
+			return nil, errSSTTruncated
+		}
+		current := r.segments[r.segment]
+		if r.pos >= len(current) {
+			r.segment++
+			r.pos = 0
+			continue
+		}
+		take := min(n-len(out), len(current)-r.pos)
+		out = append(out, current[r.pos:r.pos+take]...)
+		r.pos += take
+	}
+	return out, nil
+}
+
+// chars читает count символов строки с учётом смены ширины символов на границе записей
+func (r *sstReader) chars(count int, wide bool) (string, error) {
+	var units []uint16
+	for count > 0 {
+		if r.segment >= len(r.segments) {
+			return "", errSSTTruncated
+		}
+		if r.pos >= len(r.segments[r.segment]) {
+			r.segment++
+			r.pos = 0
+			flags, err := r.read(1)
+			if err != nil {
+				return "", err
+			}
+			wide = flags[0]&1 != 0
+			continue
+		}
+
+		size := 1
+		if wide {
+			size = 2
+		}
+		current := r.segments[r.segment]
+		take := min(count, (len(current)-r.pos)/size)
+		if take == 0 {
+			return "", errSSTTruncated
+		}
+		for i := 0; i < take; i++ {
+			if wide {
+				units = append(units, binary.LittleEndian.Uint16(current[r.pos+i*2:]))
+			} else {
+				units = append(units, uint16(current[r.pos+i]))
+			}
+		}
+		r.pos += take * size
+		count -= take
+	}
+	return string(utf16.Decode(units)), nil
+}
+
+// parseSST читает все строки общей таблицы строк
+func parseSST(segments [][]byte) ([]string, error) {
+	r := &sstReader{segments: segments}
+	head, err := r.read(8)
+	if err != nil {
+		return nil, err
+	}
+
+	// Число строк берётся из файла: в повреждённой книге оно может быть огромным.
+	// Каждая строка занимает не меньше 3 байт заголовка, поэтому больше строк в данных не поместится
+	count := int(binary.LittleEndian.Uint32(head[4:]))
+	result := make([]string, 0, min(count, r.remaining()/3))
+	for i := 0; i < count; i++ {
+		header, err := r.read(3)
+		if err != nil {
+			return nil, err
+		}
+		length := int(binary.LittleEndian.Uint16(header))
+		flags := header[2]
+
+		var runs, extra int
+		if flags&0x08 != 0 {
+			b, err := r.read(2)
+			if err != nil {
+				return nil, err
+			}
+			runs = int(binary.LittleEndian.Uint16(b))
+		}
+		if flags&0x04 != 0 {
+			b, err := r.read(4)
+			if err != nil {
+				return nil, err
+			}
+			extra = int(binary.LittleEndian.Uint32(b))
+		}
+
+		value, err := r.chars(length, flags&0x01 != 0)
+		if err != nil {
+			return nil, err
+		}
+		// Форматирование фрагментов и фонетические данные не нужны, пропускаем их
+		if err := r.skip(runs*4 + extra); err != nil {
+			return nil, err
+		}
+		result = append(result, value)
+	}
+	return result, nil
+}
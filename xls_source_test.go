@@ -0,0 +1,274 @@
+package main
+
+import (
+	"encoding/binary"
+	"math"
+	"reflect"
+	"testing"
+	"unicode/utf16"
+)
+
+// biffRec собирает запись BIFF: тип, длина и данные
+func biffRec(kind uint16, data []byte) []byte {
+	out := binary.LittleEndian.AppendUint16(nil, kind)
+	out = binary.LittleEndian.AppendUint16(out, uint16(len(data)))
+	return append(out, data...)
+}
+
+// biffBOFRec собирает запись BOF BIFF8 с типом потока dt
+func biffBOFRec(dt uint16) []byte {
+	data := binary.LittleEndian.AppendUint16(nil, biff8Version)
+	data = binary.LittleEndian.AppendUint16(data, dt)
+	return biffRec(biffBOF, append(data, make([]byte, 12)...))
+}
+
+// biffCell собирает начало записи ячейки: строка, колонка и индекс формата
+func biffCell(row, col uint16) []byte {
+	data := binary.LittleEndian.AppendUint16(nil, row)
+	data = binary.LittleEndian.AppendUint16(data, col)
+	return binary.LittleEndian.AppendUint16(data, 0x0F)
+}
+
+// biffString8 собирает строку с однобайтовыми символами и заголовком длины size байт
+func biffString8(value string, size int) []byte {
+	var out []byte
+	if size == 2 {
+		out = binary.LittleEndian.AppendUint16(nil, uint16(len(value)))
+	} else {
+		out = []byte{byte(len(value))}
+	}
+	return append(append(out, 0), value...)
+}
+
+// wideChars кодирует строку в UTF-16LE
+func wideChars(value string) []byte {
+	var out []byte
+	for _, unit := range utf16.Encode([]rune(value)) {
+		out = binary.LittleEndian.AppendUint16(out, unit)
+	}
+	return out
+}
+
+// sstHead собирает начало записи SST с числом строк count
+func sstHead(count uint32) []byte {
+	return binary.LittleEndian.AppendUint32(binary.LittleEndian.AppendUint32(nil, count), count)
+}
+
+// biffWorkbook собирает поток Workbook из глобальных записей и листов, вычисляя смещения листов
+func biffWorkbook(globals []byte, names []string, sheets [][]byte) []byte {
+	bound := func(offset int, name string) []byte {
+		data := binary.LittleEndian.AppendUint32(nil, uint32(offset))
+		return biffRec(biffBoundSheet, append(append(data, 0, 0), biffString8(name, 1)...))
+	}
+
+	size := len(biffBOFRec(0x0005)) + len(globals) + len(biffRec(biffEOF, nil))
+	for _, name := range names {
+		size += len(bound(0, name))
+	}
+
+	stream := biffBOFRec(0x0005)
+	offset := size
+	for i, name := range names {
+		stream = append(stream, bound(offset, name)...)
+		offset += len(sheets[i])
+	}
+	stream = append(append(stream, globals...), biffRec(biffEOF, nil)...)
+	for _, sheet := range sheets {
+		stream = append(stream, sheet...)
+	}
+	return stream
+}
+
+// biffSheet собирает поток листа из записей ячеек
+func biffSheet(records ...[]byte) []byte {
+	sheet := biffBOFRec(0x0010)
+	for _, record := range records {
+		sheet = append(sheet, record...)
+	}
+	return append(sheet, biffRec(biffEOF, nil)...)
+}
+
+func TestParseSST(t *testing.T) {
+	tests := []struct {
+		name     string
+		segments [][]byte
+		want     []string
+		wantErr  bool
+	}{
+		{
+			name:     "однобайтовые строки",
+			segments: [][]byte{append(append(sstHead(2), biffString8("Employee", 2)...), biffString8("Dev", 2)...)},
+			want:     []string{"Employee", "Dev"},
+		},
+		{
+			name: "строка продолжается в CONTINUE двухбайтовыми символами",
+			segments: [][]byte{
+				append(sstHead(2), append([]byte{7, 0, 0}, "ivan"...)...),
+				append(append([]byte{1}, wideChars("ов!")...), biffString8("Prod", 2)...),
+			},
+			want: []string{"ivanов!", "Prod"},
+		},
+		{
+			name: "форматирование и фонетические данные пропускаются",
+			segments: [][]byte{append(append(sstHead(2),
+				append([]byte{2, 0, 0x0C, 1, 0, 2, 0, 0, 0}, append([]byte("ab"), make([]byte, 4+2)...)...)...),
+				biffString8("c", 2)...)},
+			want: []string{"ab", "c"},
+		},
+		{
+			name:     "огромное число строк в повреждённом файле",
+			segments: [][]byte{append(binary.LittleEndian.AppendUint32(sstHead(0)[:4], math.MaxUint32), biffString8("x", 2)...)},
+			wantErr:  true,
+		},
+		{
+			name: "огромный размер фонетических данных",
+			segments: [][]byte{append(sstHead(1),
+				append([]byte{1, 0, 0x04}, append(binary.LittleEndian.AppendUint32(nil, math.MaxUint32), 'x')...)...)},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSST(tt.segments)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSST() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseSST() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeRK(t *testing.T) {
+	float := func(value float64) uint32 { return uint32(math.Float64bits(value) >> 32) }
+	negative := int32(-7)
+	tests := []struct {
+		name string
+		rk   uint32
+		want float64
+	}{
+		{"целое", 42<<2 | 0x02, 42},
+		{"отрицательное целое", uint32(negative<<2) | 0x02, -7},
+		{"целое, делённое на 100", 150<<2 | 0x03, 1.5},
+		{"число с плавающей точкой", float(2.5), 2.5},
+		{"число с плавающей точкой, делённое на 100", float(1234) | 0x01, 12.34},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := decodeRK(tt.rk); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("decodeRK(%#x) = %v, want %v", tt.rk, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseBIFFWorkbook(t *testing.T) {
+	sst := biffRec(biffSST, append(append(sstHead(2), biffString8("Role name", 2)...), biffString8("viewer", 2)...))
+	labelSST := func(row, col uint16, index uint32) []byte {
+		return biffRec(biffLabelSST, binary.LittleEndian.AppendUint32(biffCell(row, col), index))
+	}
+	rk := func(row, col uint16, value uint32) []byte {
+		return biffRec(biffRK, binary.LittleEndian.AppendUint32(biffCell(row, col), value))
+	}
+
+	mulRK := binary.LittleEndian.AppendUint16(nil, 1)
+	mulRK = binary.LittleEndian.AppendUint16(mulRK, 1)
+	for _, value := range []uint32{150<<2 | 0x03, 100<<2 | 0x02} {
+		mulRK = binary.LittleEndian.AppendUint32(binary.LittleEndian.AppendUint16(mulRK, 0x0F), value)
+	}
+	mulRK = binary.LittleEndian.AppendUint16(mulRK, 2)
+
+	formulaString := append(biffCell(0, 0), 0, 0, 0, 0, 0, 0, 0xFF, 0xFF)
+	formulaString = append(formulaString, make([]byte, 6)...)
+	number := binary.LittleEndian.AppendUint64(biffCell(0, 1), math.Float64bits(3.25))
+
+	tests := []struct {
+		name    string
+		stream  []byte
+		want    map[string][][]string
+		wantErr bool
+	}{
+		{
+			name: "общая таблица строк и числа RK/MULRK",
+			stream: biffWorkbook(sst, []string{"Request"}, [][]byte{biffSheet(
+				labelSST(0, 0, 0), labelSST(0, 1, 1), rk(1, 0, 42<<2|0x02), biffRec(biffMulRK, mulRK),
+			)}),
+			want: map[string][][]string{"Request": {{"Role name", "viewer"}, {"42", "1.5", "100"}}},
+		},
+		{
+			name: "строковый результат формулы, число и LABEL",
+			stream: biffWorkbook(nil, []string{"Request"}, [][]byte{biffSheet(
+				biffRec(biffFormula, formulaString), biffRec(biffString, biffString8("calc", 2)),
+				biffRec(biffNumber, number),
+				biffRec(biffLabel, append(biffCell(1, 0), biffString8("ivanov", 2)...)),
+			)}),
+			want: map[string][][]string{"Request": {{"calc", "3.25"}, {"ivanov"}}},
+		},
+		{
+			name: "встроенная диаграмма не завершает лист",
+			stream: biffWorkbook(sst, []string{"Request", "Users"}, [][]byte{
+				biffSheet(labelSST(0, 0, 0), biffBOFRec(0x0020), labelSST(5, 5, 1), biffRec(biffEOF, nil), labelSST(1, 0, 1)),
+				biffSheet(biffRec(biffLabel, append(biffCell(0, 0), biffString8("sidorov", 2)...))),
+			}),
+			want: map[string][][]string{"Request": {{"Role name"}, {"viewer"}}, "Users": {{"sidorov"}}},
+		},
+		{
+			name:    "книга защищена паролем",
+			stream:  biffWorkbook(biffRec(biffFilePass, make([]byte, 6)), nil, nil),
+			wantErr: true,
+		},
+		{
+			name:    "не BIFF8",
+			stream:  biffRec(biffBOF, []byte{0x00, 0x05, 0x05, 0x00}),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sheets, err := parseBIFFWorkbook(tt.stream)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseBIFFWorkbook() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			got := make(map[string][][]string, len(sheets))
+			for _, sheet := range sheets {
+				got[sheet.Name] = sheet.Rows
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseBIFFWorkbook() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestXLSSourceRead(t *testing.T) {
+	tables, err := xlsSource{}.Read("testdata/request.xls")
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if len(tables) != 1 {
+		t.Fatalf("Read() вернул таблиц: %d, ожидалась 1", len(tables))
+	}
+
+	table := tables[0]
+	wantHeader := []string{"Keycloak type", "Keycloak environment", "Action", "Client ID", "Role name", "User logins"}
+	if !reflect.DeepEqual(table.Header, wantHeader) {
+		t.Errorf("Header = %q, want %q", table.Header, wantHeader)
+	}
+	wantRows := [][]string{
+		{"Employee", "Dev", "Associate users with role", "app", "viewer", "ivanov, петров"},
+		{"42", "1.5", "100", "calc", "3.25", "TRUE"},
+	}
+	if !reflect.DeepEqual(table.Rows, wantRows) {
+		t.Errorf("Rows = %q, want %q", table.Rows, wantRows)
+	}
+	if len(table.Sheets) != 2 || table.Sheets[1].Name != "Users" {
+		t.Errorf("Sheets = %v, ожидались листы Request и Users", table.Sheets)
+	}
+}